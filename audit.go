@@ -0,0 +1,138 @@
+package tyr
+
+import (
+	"context"
+	"reflect"
+	"time"
+)
+
+// Audit names the audit columns of a model, a column left empty is not
+// written.
+type Audit struct {
+	CreatedAt string
+	UpdatedAt string
+	CreatedBy string
+	UpdatedBy string
+
+	// Clock stamps CreatedAt and UpdatedAt, time.Now when nil.
+	Clock func() time.Time
+	// DBNow stamps with the database clock, e.g. now(), instead of Clock.
+	DBNow bool
+}
+
+// Auditor opts a model into the audit columns written by Insert,
+// Inserts, Upsert and Updates, e.g.
+//
+//	func (Game) Audit() tyr.Audit {
+//		return tyr.Audit{CreatedAt: "create_date", UpdatedAt: "write_date"}
+//	}
+type Auditor interface {
+	Audit() Audit
+}
+
+type actorKey struct{}
+
+// WithActor returns a copy of the context carrying the actor written to
+// the CreatedBy and UpdatedBy audit columns.
+func WithActor(ctx context.Context, actor interface{}) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFromContext returns the actor set by WithActor.
+func ActorFromContext(ctx context.Context) (interface{}, bool) {
+	actor := ctx.Value(actorKey{})
+	return actor, actor != nil
+}
+
+// WithContext binds the query to the context, whose actor is written to
+// the CreatedBy and UpdatedBy audit columns when the statement is built.
+func (s *Query) WithContext(ctx context.Context) *Query {
+	q := s.clone()
+	q.ctx = ctx
+	return q
+}
+
+// actorArg is bound to the actor of the query context when the statement
+// is built.
+type actorArg struct{}
+
+// dbNow renders the clock of the database instead of binding a value.
+type dbNow struct{}
+
+type auditColumn struct {
+	name  string
+	value interface{}
+}
+
+// auditOf returns the audit of the model, or of the models of a slice.
+func auditOf(model interface{}) (Audit, bool) {
+	v := reflect.ValueOf(model)
+	if v.Kind() == reflect.Slice {
+		if v.Len() < 1 {
+			return Audit{}, false
+		}
+		model = v.Index(0).Interface()
+	}
+	if m, ok := model.(Auditor); ok && !isNilModel(model) {
+		return m.Audit(), true
+	}
+	return Audit{}, false
+}
+
+func (a Audit) now() interface{} {
+	if a.DBNow {
+		return dbNow{}
+	}
+	if a.Clock != nil {
+		return a.Clock().UTC()
+	}
+	return time.Now().UTC()
+}
+
+// columns lists the audit columns stamped on insert, or on update.
+func (a Audit) columns(insert bool) []auditColumn {
+	now := a.now()
+	columns := make([]auditColumn, 0)
+	if insert && len(a.CreatedAt) > 0 {
+		columns = append(columns, auditColumn{a.CreatedAt, now})
+	}
+	if len(a.UpdatedAt) > 0 {
+		columns = append(columns, auditColumn{a.UpdatedAt, now})
+	}
+	if insert && len(a.CreatedBy) > 0 {
+		columns = append(columns, auditColumn{a.CreatedBy, actorArg{}})
+	}
+	if len(a.UpdatedBy) > 0 {
+		columns = append(columns, auditColumn{a.UpdatedBy, actorArg{}})
+	}
+	return columns
+}
+
+// names lists the audit columns, which are not taken from the model
+// fields.
+func (a Audit) names() []string {
+	names := make([]string, 0)
+	for _, name := range []string{a.CreatedAt, a.UpdatedAt, a.CreatedBy, a.UpdatedBy} {
+		if len(name) > 0 {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// created lists the audit columns kept on upsert conflicts.
+func (a Audit) created() []string {
+	return []string{a.CreatedAt, a.CreatedBy}
+}
+
+// bindArg resolves an arg bound when the statement is built.
+func (r *SQL) bindArg(arg interface{}) interface{} {
+	if _, ok := arg.(actorArg); !ok {
+		return arg
+	}
+	if r.query.ctx == nil {
+		return nil
+	}
+	actor, _ := ActorFromContext(r.query.ctx)
+	return actor
+}
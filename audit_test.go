@@ -0,0 +1,93 @@
+package tyr
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var invoiceClock = time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+
+type Invoice struct {
+	ID        int       `json:"id" sql:"id"`
+	Total     int       `json:"total" sql:"total"`
+	UpdatedAt time.Time `json:"updated_at" sql:"updated_at"`
+}
+
+func (Invoice) TableName() string {
+	return "invoices"
+}
+
+func (Invoice) Audit() Audit {
+	return Audit{
+		CreatedAt: "created_at",
+		UpdatedAt: "updated_at",
+		CreatedBy: "created_by",
+		UpdatedBy: "updated_by",
+		Clock:     func() time.Time { return invoiceClock },
+	}
+}
+
+type Ledger struct {
+	ID    int `json:"id" sql:"id"`
+	Total int `json:"total" sql:"total"`
+}
+
+func (Ledger) TableName() string {
+	return "ledgers"
+}
+
+func (Ledger) Audit() Audit {
+	return Audit{CreatedAt: "created_at", UpdatedAt: "updated_at", DBNow: true}
+}
+
+func TestRawQuery_AuditOptIn(t *testing.T) {
+	query, args := Build().Insert(&Player{Name: "sury"}).ToSQL()
+	assert.Equal(t, "INSERT INTO players (active, name) VALUES ($1, $2) RETURNING id", query)
+	assert.Equal(t, 2, len(args))
+
+	query, _ = Build().Upsert(&Player{ID: 1, Name: "sury"}).ToSQL()
+	assert.Equal(t, "INSERT INTO players (active, id, name) VALUES ($1, $2, $3) ON CONFLICT (id) DO UPDATE SET active = EXCLUDED.active, name = EXCLUDED.name RETURNING id", query)
+}
+
+func TestRawQuery_AuditColumns(t *testing.T) {
+	ctx := WithActor(context.Background(), "sury")
+	actor, ok := ActorFromContext(ctx)
+	assert.True(t, ok)
+	assert.Equal(t, "sury", actor)
+
+	insert := Build().Insert(&Invoice{Total: 10, UpdatedAt: time.Now()})
+	query, args := insert.WithContext(ctx).ToSQL()
+	assert.Equal(t, "INSERT INTO invoices (total, created_at, updated_at, created_by, updated_by) VALUES ($1, $2, $3, $4, $5) RETURNING id", query)
+	assert.Equal(t, []interface{}{10, invoiceClock, invoiceClock, "sury", "sury"}, args)
+
+	_, args = insert.ToSQL()
+	assert.Equal(t, []interface{}{10, invoiceClock, invoiceClock, nil, nil}, args)
+
+	query, args = Build().WithContext(ctx).Updates(&Invoice{ID: 7, Total: 12}).Where(Eq("id", 7)).ToSQL()
+	assert.Equal(t, "UPDATE invoices SET total = $1, updated_at = $2, updated_by = $3 WHERE id = $4 RETURNING id", query)
+	assert.Equal(t, []interface{}{12, invoiceClock, "sury", 7}, args)
+
+	query, _ = Build().Upsert(&Invoice{ID: 7, Total: 12}).ToSQL()
+	assert.Equal(t, "INSERT INTO invoices (id, total, created_at, updated_at, created_by, updated_by) VALUES ($1, $2, $3, $4, $5, $6) ON CONFLICT (id) DO UPDATE SET total = EXCLUDED.total, updated_at = EXCLUDED.updated_at, updated_by = EXCLUDED.updated_by RETURNING id", query)
+}
+
+func TestRawQuery_AuditDBNow(t *testing.T) {
+	query, args := Build().Insert(&Ledger{Total: 3}).ToSQL()
+	assert.Equal(t, "INSERT INTO ledgers (total, created_at, updated_at) VALUES ($1, now(), now()) RETURNING id", query)
+	assert.Equal(t, 1, len(args))
+
+	query, args = Build().SetDialect(MYSQL).Inserts([]*Ledger{{Total: 3}, {Total: 4}}).ToSQL()
+	assert.Equal(t, "INSERT INTO ledgers (total, created_at, updated_at) VALUES (?, NOW(), NOW()), (?, NOW(), NOW())", query)
+	assert.Equal(t, []interface{}{3, 4}, args)
+
+	query, _ = Build().Updates(&Ledger{ID: 1, Total: 5}).Where(Eq("id", 1)).ToSQL()
+	assert.Equal(t, "UPDATE ledgers SET total = $1, updated_at = now() WHERE id = $2 RETURNING id", query)
+
+	_, _, err := Build().Updates(&Player{ID: 1}).Fields("id").Build()
+	assert.Error(t, err)
+	_, _, err = Build().Updates(&Comment{ID: 1}).Build()
+	assert.Error(t, err)
+}
@@ -4,8 +4,11 @@ import (
 	"database/sql"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"reflect"
+	"sort"
 	"strings"
+	"sync"
 )
 
 var (
@@ -36,95 +39,363 @@ func CloneStruct(src, dest interface{}) error {
 	return json.Unmarshal(b, dest)
 }
 
-func elemTypePtr(i interface{}) reflect.Type {
-	t := reflect.TypeOf(i)
-	switch {
-	case t.Kind() == reflect.Ptr:
-		t = t.Elem()
-	case t.Kind() != reflect.Struct:
-		panic(NotPointer)
+// scanPlans caches the scan plan of a struct type and result columns.
+var scanPlans sync.Map
+
+// typeFields caches the fields of a struct type by column name.
+var typeFields sync.Map
+
+type planKey struct {
+	t       reflect.Type
+	columns string
+}
+
+// fieldPath is the index of a field, nested is the length of the index
+// of the outermost nested struct pointer on the way, 0 when none.
+type fieldPath struct {
+	index  []int
+	nested int
+	tagged bool
+}
+
+// scanPlan lists the index of the field scanned by each column, nil for
+// a column without field. Columns of a nested struct pointer share a
+// group, which is only allocated when one of them is not NULL.
+type scanPlan struct {
+	fields [][]int
+	group  []int
+	groups int
+
+	// unmapped, unused and duplicated fail a Strict scan
+	unmapped   []string
+	unused     []string
+	duplicated []string
+}
+
+func planOf(t reflect.Type, columns []string) scanPlan {
+	key := planKey{t: t, columns: strings.Join(columns, "\x00")}
+	if plan, ok := scanPlans.Load(key); ok {
+		return plan.(scanPlan)
 	}
-	return t
+	fields := fieldsOf(t)
+	plan := scanPlan{fields: make([][]int, len(columns)), group: make([]int, len(columns))}
+	groups := make(map[string]int)
+	used := make(map[string]bool, len(columns))
+	for x, column := range columns {
+		plan.group[x] = -1
+		path, ok := fields[strings.ToLower(column)]
+		if !ok {
+			plan.unmapped = append(plan.unmapped, column)
+			continue
+		}
+		if used[strings.ToLower(column)] {
+			// the first column wins, e.g. g.id over u.id of g.*, u.*
+			plan.duplicated = append(plan.duplicated, column)
+			continue
+		}
+		used[strings.ToLower(column)] = true
+		plan.fields[x] = path.index
+		if path.nested > 0 {
+			key := fmt.Sprint(path.index[:path.nested])
+			if _, ok := groups[key]; !ok {
+				groups[key] = len(groups)
+			}
+			plan.group[x] = groups[key]
+		}
+	}
+	plan.groups = len(groups)
+	for column, path := range fields {
+		if path.tagged && !used[column] {
+			plan.unused = append(plan.unused, fieldName(t, path.index))
+		}
+	}
+	sort.Strings(plan.unused)
+	stored, _ := scanPlans.LoadOrStore(key, plan)
+	return stored.(scanPlan)
 }
 
-func colToFieldIndex(t reflect.Type, columns []string) ([][]int, error) {
-	colToFieldIndex := make([][]int, len(columns))
-	for x := range columns {
-		colName := strings.ToLower(columns[x])
-		field, found := t.FieldByNameFunc(func(fieldName string) bool {
-			field, _ := t.FieldByName(fieldName)
-			cArguments := strings.Split(field.Tag.Get("sql"), ",")
-			fieldName = cArguments[0]
+// colToFieldIndex maps the columns to the fields whose sql tag, or name
+// when untagged, matches case-insensitively.
+func colToFieldIndex(t reflect.Type, columns []string) [][]int {
+	return planOf(t, columns).fields
+}
 
-			if fieldName == "-" {
-				return false
-			} else if fieldName == "" {
-				fieldName = field.Name
+// fieldsOf maps the lower cased column names of the struct type to its
+// fields. The columns of a field tagged nested, e.g. `sql:"u,nested"`,
+// are prefixed by its name, u__name, and embedded structs are flattened.
+func fieldsOf(t reflect.Type) map[string]fieldPath {
+	if fields, ok := typeFields.Load(t); ok {
+		return fields.(map[string]fieldPath)
+	}
+	fields := make(map[string]fieldPath)
+	structFields(t, "", nil, 0, fields)
+	stored, _ := typeFields.LoadOrStore(t, fields)
+	return stored.(map[string]fieldPath)
+}
+
+func structFields(t reflect.Type, prefix string, index []int, nested int, fields map[string]fieldPath) {
+	// fields of the struct itself take precedence over the nested ones
+	deferred := make([]func(), 0)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, opts := parseTag(field.Tag.Get("sql"))
+		if name == "-" {
+			continue
+		}
+		path := append(append([]int(nil), index...), i)
+		ft := indirectType(field.Type)
+		pointer := field.Type.Kind() == reflect.Ptr
+		if opts.Contains("nested") || isEmbedded(field, name) {
+			if ft.Kind() != reflect.Struct || (pointer && len(field.PkgPath) > 0) {
+				continue
+			}
+			sub, group := prefix, nested
+			if opts.Contains("nested") {
+				if len(name) < 1 {
+					name = field.Name
+				}
+				sub = prefix + strings.ToLower(name) + "__"
+			}
+			if pointer && group == 0 {
+				group = len(path)
 			}
+			deferred = append(deferred, func() { structFields(ft, sub, path, group, fields) })
+			continue
+		}
+		if len(field.PkgPath) > 0 {
+			continue
+		}
+		tagged := len(name) > 0
+		if !tagged {
+			name = field.Name
+		}
+		key := prefix + strings.ToLower(name)
+		if _, ok := fields[key]; !ok {
+			fields[key] = fieldPath{index: path, nested: nested, tagged: tagged}
+		}
+	}
+	for _, fn := range deferred {
+		fn()
+	}
+}
+
+// fieldName names the field at index by its path from t, leaving out the
+// embedded structs, e.g. Owner.Name.
+func fieldName(t reflect.Type, index []int) string {
+	names := make([]string, 0, len(index))
+	for _, x := range index {
+		field := indirectType(t).Field(x)
+		if !field.Anonymous {
+			names = append(names, field.Name)
+		}
+		t = field.Type
+	}
+	return strings.Join(names, ".")
+}
 
-			return colName == strings.ToLower(fieldName)
-		})
-		if found {
-			colToFieldIndex[x] = field.Index
+// fieldByIndex is reflect.Value.FieldByIndex allocating the nil struct
+// pointers on the way.
+func fieldByIndex(v reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
 		}
+		v = v.Field(x)
 	}
-	return colToFieldIndex, nil
+	return v
 }
 
+// ScanMode decides how ScanRow, ScanOne and ScanAll handle the columns
+// without field and the fields without column.
+type ScanMode int
+
+const (
+	// Lenient discards the columns without field and the repeats of a
+	// column, and leaves the fields without column untouched.
+	Lenient ScanMode = iota
+	// Strict fails the scan with a *ScanError when a column has no field,
+	// a tagged field has no column or a column is repeated, e.g. to catch
+	// a renamed column or the ids of a g.*, u.* join.
+	Strict
+)
+
+// ScanError lists the columns of a result without field, the tagged
+// fields of the destination without column and the columns repeated in
+// the result, returned by Strict scans.
+type ScanError struct {
+	Type       reflect.Type
+	Columns    []string
+	Fields     []string
+	Duplicates []string
+}
+
+func (e *ScanError) Error() string {
+	messages := make([]string, 0, 3)
+	if len(e.Columns) > 0 {
+		messages = append(messages, fmt.Sprintf("columns without field: %s", strings.Join(e.Columns, ", ")))
+	}
+	if len(e.Fields) > 0 {
+		messages = append(messages, fmt.Sprintf("fields without column: %s", strings.Join(e.Fields, ", ")))
+	}
+	if len(e.Duplicates) > 0 {
+		messages = append(messages, fmt.Sprintf("duplicate columns: %s", strings.Join(e.Duplicates, ", ")))
+	}
+	return fmt.Sprintf("scan %s: %s", e.Type, strings.Join(messages, "; "))
+}
+
+// check returns the *ScanError of the plan in Strict mode.
+func (m ScanMode) check(t reflect.Type, plan scanPlan) error {
+	if m != Strict || (len(plan.unmapped) < 1 && len(plan.unused) < 1 && len(plan.duplicated) < 1) {
+		return nil
+	}
+	return &ScanError{Type: t, Columns: plan.unmapped, Fields: plan.unused, Duplicates: plan.duplicated}
+}
+
+// ScanRow scans the current row into the fields of the struct pointed to
+// by dest, columns without a field are skipped and a repeated column
+// only fills its field once, from its first occurrence. The alias__column
+// columns of a Join projection fill the field tagged `sql:"alias,nested"`,
+// a nested struct pointer is left nil when all its columns are NULL.
 func ScanRow(rs *sql.Rows, dest interface{}) error {
-	dpv := elemTypePtr(dest)
+	return Lenient.ScanRow(rs, dest)
+}
+
+// ScanOne advances to the first row and scans it into the struct pointed
+// to by dest, returning sql.ErrNoRows when there is none.
+func ScanOne(rs *sql.Rows, dest interface{}) error {
+	return Lenient.ScanOne(rs, dest)
+}
+
+// ScanAll scans the remaining rows and appends them to the slice pointed
+// to by dest, a slice of structs or of struct pointers.
+func ScanAll(rs *sql.Rows, dest interface{}) error {
+	return Lenient.ScanAll(rs, dest)
+}
+
+// ScanRow is ScanRow in the scan mode.
+func (m ScanMode) ScanRow(rs *sql.Rows, dest interface{}) error {
+	d := reflect.ValueOf(dest)
+	if d.Kind() != reflect.Ptr {
+		return NotPointer
+	}
+	if d.IsNil() {
+		return NilPointer
+	}
+	v := d.Elem()
+	if v.Kind() != reflect.Struct {
+		return fmt.Errorf("scan destination %T is not a pointer to struct", dest)
+	}
 
 	columns, err := rs.Columns()
 	if err != nil {
 		return err
 	}
+	plan := planOf(v.Type(), columns)
+	if err := m.check(v.Type(), plan); err != nil {
+		return err
+	}
+	return scanStruct(rs, v, plan)
+}
 
-	colToFieldIdx, erCol := colToFieldIndex(dpv, columns)
-	if erCol != nil {
-		return erCol
-	}
-
-	v := reflect.New(dpv)
-	pointers := make([]interface{}, len(columns))
-	for x := range columns {
-		f := v.Elem()
-		f = f.FieldByIndex(colToFieldIdx[x])
-		target := f.Addr().Interface()
-		switch f.Kind() {
-		case reflect.String,
-			reflect.Bool,
-			reflect.Float64,
-			reflect.Float32,
-			reflect.Int,
-			reflect.Int32,
-			reflect.Int64:
-			pointers[x] = target
-			continue
+// ScanOne is ScanOne in the scan mode.
+func (m ScanMode) ScanOne(rs *sql.Rows, dest interface{}) error {
+	if !rs.Next() {
+		if err := rs.Err(); err != nil {
+			return err
 		}
-		switch f.Type() {
-		case reflect.TypeOf(NullString{}),
-			reflect.TypeOf(NullInt64{}),
-			reflect.TypeOf(NullTime{}),
-			reflect.TypeOf(NullFloat64{}),
-			reflect.TypeOf(NullBool{}):
-		default:
-			pointers[x] = new(interface{})
+		return sql.ErrNoRows
+	}
+	return m.ScanRow(rs, dest)
+}
+
+// ScanAll is ScanAll in the scan mode, a Strict scan fails before
+// scanning any row.
+func (m ScanMode) ScanAll(rs *sql.Rows, dest interface{}) error {
+	d := reflect.ValueOf(dest)
+	if d.Kind() != reflect.Ptr {
+		return NotPointer
+	}
+	if d.IsNil() {
+		return NilPointer
+	}
+	slice := d.Elem()
+	if slice.Kind() != reflect.Slice {
+		return fmt.Errorf("scan destination %T is not a pointer to slice", dest)
+	}
+	elem := slice.Type().Elem()
+	t := elem
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return fmt.Errorf("scan destination %T is not a slice of structs", dest)
+	}
+
+	columns, err := rs.Columns()
+	if err != nil {
+		return err
+	}
+	plan := planOf(t, columns)
+	if err := m.check(t, plan); err != nil {
+		return err
+	}
+	for rs.Next() {
+		row := reflect.New(t)
+		if err := scanStruct(rs, row.Elem(), plan); err != nil {
+			return err
+		}
+		if elem.Kind() == reflect.Ptr {
+			slice.Set(reflect.Append(slice, row))
 			continue
 		}
-		pointers[x] = target
+		slice.Set(reflect.Append(slice, row.Elem()))
 	}
+	return rs.Err()
+}
 
-	if er := rs.Scan(pointers...); er != nil {
-		return er
+// scanStruct scans the current row into the fields of v along the plan.
+func scanStruct(rs *sql.Rows, v reflect.Value, plan scanPlan) error {
+	var discard interface{}
+	var held []interface{}
+	pointers := make([]interface{}, len(plan.fields))
+	for x, index := range plan.fields {
+		switch {
+		case index == nil:
+			pointers[x] = &discard
+		case plan.group[x] >= 0:
+			// hold the column until its nested struct is known not NULL
+			if held == nil {
+				held = make([]interface{}, len(plan.fields))
+			}
+			pointers[x] = &held[x]
+		default:
+			pointers[x] = fieldByIndex(v, index).Addr().Interface()
+		}
+	}
+	if err := rs.Scan(pointers...); err != nil || held == nil {
+		return err
 	}
 
-	dataMap := make(map[string]interface{}, len(columns))
-	for idx, col := range columns {
-		dataMap[col] = pointers[idx]
+	present := make([]bool, plan.groups)
+	for x, group := range plan.group {
+		if group >= 0 && held[x] != nil {
+			present[group] = true
+		}
+	}
+	rescan := false
+	for x, group := range plan.group {
+		if group < 0 || !present[group] {
+			pointers[x] = &discard
+			continue
+		}
+		pointers[x] = fieldByIndex(v, plan.fields[x]).Addr().Interface()
+		rescan = true
 	}
-	// struct scan
-	if er := CloneStruct(&dataMap, &dest); er != nil {
-		return er
+	if !rescan {
+		return nil
 	}
-	return nil
+	return rs.Scan(pointers...)
 }
@@ -0,0 +1,343 @@
+package tyr
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeDriver serves the rows registered for a query, so scanning can be
+// tested without a database.
+type fakeDriver struct{}
+
+type fakeResult struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+var fakeResults sync.Map
+
+func init() {
+	sql.Register("tyrfake", fakeDriver{})
+}
+
+func openFake(t testing.TB, query string, columns []string, rows ...[]driver.Value) *sql.DB {
+	fakeResults.Store(query, fakeResult{columns: columns, rows: rows})
+	db, err := sql.Open("tyrfake", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func (fakeDriver) Open(string) (driver.Conn, error) { return fakeConn{}, nil }
+
+type fakeConn struct{}
+
+func (fakeConn) Prepare(query string) (driver.Stmt, error) { return fakeStmt{query}, nil }
+func (fakeConn) Close() error                              { return nil }
+func (fakeConn) Begin() (driver.Tx, error)                 { return fakeTx{}, nil }
+
+type fakeTx struct{}
+
+func (fakeTx) Commit() error   { return nil }
+func (fakeTx) Rollback() error { return nil }
+
+// fakeInsert reports its id through LastInsertId.
+type fakeInsert int64
+
+func (id fakeInsert) LastInsertId() (int64, error) { return int64(id), nil }
+func (fakeInsert) RowsAffected() (int64, error)    { return 1, nil }
+
+type fakeStmt struct {
+	query string
+}
+
+func (fakeStmt) Close() error  { return nil }
+func (fakeStmt) NumInput() int { return -1 }
+func (fakeStmt) Exec([]driver.Value) (driver.Result, error) {
+	return fakeInsert(7), nil
+}
+func (s fakeStmt) Query([]driver.Value) (driver.Rows, error) {
+	result, ok := fakeResults.Load(s.query)
+	if !ok {
+		return nil, fmt.Errorf("no result for %q", s.query)
+	}
+	return &fakeRows{result: result.(fakeResult)}, nil
+}
+
+type fakeRows struct {
+	result fakeResult
+	n      int
+}
+
+func (r *fakeRows) Columns() []string { return r.result.columns }
+func (r *fakeRows) Close() error      { return nil }
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.n >= len(r.result.rows) {
+		return io.EOF
+	}
+	copy(dest, r.result.rows[r.n])
+	r.n++
+	return nil
+}
+
+type scanned struct {
+	ID       int64      `json:"id" sql:"id"`
+	Name     string     `json:"full_name" sql:"name"`
+	Payload  []byte     `json:"payload" sql:"payload"`
+	Created  time.Time  `json:"created" sql:"created_at"`
+	Rate     NullInt64  `json:"rate" sql:"rate"`
+	Note     NullString `json:"note" sql:"note"`
+	Count    *int       `json:"count" sql:"count"`
+	Enabled  bool
+	Password string `json:"-" sql:"-"`
+}
+
+var scannedColumns = []string{"id", "name", "payload", "created_at", "rate", "note", "count", "enabled", "password", "extra"}
+
+func scannedRow(created time.Time) []driver.Value {
+	return []driver.Value{int64(7), "sury", []byte{0, 1, 2}, created, int64(9), nil, int64(3), true, "secret", "ignored"}
+}
+
+func TestScanRow(t *testing.T) {
+	created := time.Date(2020, 1, 2, 3, 4, 5, 678901234, time.UTC)
+	db := openFake(t, "scan row", scannedColumns, scannedRow(created))
+	defer db.Close()
+
+	rows, err := db.Query("scan row")
+	assert.NoError(t, err)
+	defer rows.Close()
+	assert.True(t, rows.Next())
+
+	var row scanned
+	assert.NoError(t, ScanRow(rows, &row))
+	count := 3
+	assert.Equal(t, scanned{
+		ID:      7,
+		Name:    "sury",
+		Payload: []byte{0, 1, 2},
+		Created: created,
+		Rate:    Int64(9),
+		Count:   &count,
+		Enabled: true,
+	}, row)
+
+	assert.Equal(t, NotPointer, ScanRow(rows, row))
+	assert.Equal(t, NilPointer, ScanRow(rows, (*scanned)(nil)))
+	var name string
+	assert.Error(t, ScanRow(rows, &name))
+}
+
+func TestScanRowTypeMismatch(t *testing.T) {
+	db := openFake(t, "scan mismatch", []string{"id"}, []driver.Value{"seven"})
+	defer db.Close()
+
+	rows, err := db.Query("scan mismatch")
+	assert.NoError(t, err)
+	defer rows.Close()
+	assert.True(t, rows.Next())
+
+	var row scanned
+	assert.Error(t, ScanRow(rows, &row))
+}
+
+func TestScanAll(t *testing.T) {
+	columns := []string{"id", "name"}
+	db := openFake(t, "scan all", columns, []driver.Value{int64(1), "a"}, []driver.Value{int64(2), "b"})
+	defer db.Close()
+
+	rows, err := db.Query("scan all")
+	assert.NoError(t, err)
+	values := []scanned{{ID: 9}}
+	assert.NoError(t, ScanAll(rows, &values))
+	assert.Equal(t, []scanned{{ID: 9}, {ID: 1, Name: "a"}, {ID: 2, Name: "b"}}, values)
+
+	rows, err = db.Query("scan all")
+	assert.NoError(t, err)
+	var pointers []*scanned
+	assert.NoError(t, ScanAll(rows, &pointers))
+	assert.Equal(t, []*scanned{{ID: 1, Name: "a"}, {ID: 2, Name: "b"}}, pointers)
+
+	rows, err = db.Query("scan all")
+	assert.NoError(t, err)
+	defer rows.Close()
+	assert.Equal(t, NotPointer, ScanAll(rows, pointers))
+	assert.Error(t, ScanAll(rows, &scanned{}))
+	assert.Error(t, ScanAll(rows, &[]string{}))
+}
+
+func TestScanOne(t *testing.T) {
+	db := openFake(t, "scan one", []string{"id", "name"}, []driver.Value{int64(1), "a"}, []driver.Value{int64(2), "b"})
+	defer db.Close()
+	defer openFake(t, "scan none", []string{"id", "name"}).Close()
+
+	rows, err := db.Query("scan one")
+	assert.NoError(t, err)
+	var row scanned
+	assert.NoError(t, ScanOne(rows, &row))
+	assert.Equal(t, scanned{ID: 1, Name: "a"}, row)
+	assert.NoError(t, rows.Close())
+
+	rows, err = db.Query("scan none")
+	assert.NoError(t, err)
+	assert.Equal(t, sql.ErrNoRows, ScanOne(rows, &row))
+	assert.NoError(t, rows.Close())
+}
+
+func TestScanStrict(t *testing.T) {
+	created := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	db := openFake(t, "scan strict", scannedColumns, scannedRow(created))
+	defer db.Close()
+	defer openFake(t, "scan strict partial", []string{"ID", "name"}, []driver.Value{int64(1), "a"}).Close()
+	defer openFake(t, "scan strict exact", []string{"game_id", "game_code", "u__id", "u__name", "m__id", "m__name"}).Close()
+	defer openFake(t, "scan strict duplicate", []string{"id", "active", "name", "id", "name"},
+		[]driver.Value{int64(1), true, "a", int64(2), "b"},
+	).Close()
+	defer openFake(t, "scan strict nested", []string{"game_id", "game_code", "u__id", "u__name", "m__id"}).Close()
+
+	rows, err := db.Query("scan strict")
+	assert.NoError(t, err)
+	assert.True(t, rows.Next())
+	var row scanned
+	err = Strict.ScanRow(rows, &row)
+	assert.Equal(t, &ScanError{Type: reflect.TypeOf(row), Columns: []string{"password", "extra"}}, err)
+	assert.Equal(t, "scan tyr.scanned: columns without field: password, extra", err.Error())
+	assert.Equal(t, scanned{}, row)
+	assert.NoError(t, Lenient.ScanRow(rows, &row))
+	assert.NoError(t, rows.Close())
+
+	rows, err = db.Query("scan strict partial")
+	assert.NoError(t, err)
+	err = Strict.ScanOne(rows, &row)
+	assert.EqualError(t, err, "scan tyr.scanned: fields without column: Count, Created, Note, Payload, Rate")
+	assert.NoError(t, rows.Close())
+
+	rows, err = db.Query("scan strict exact")
+	assert.NoError(t, err)
+	var owners []gameOwner
+	assert.NoError(t, Strict.ScanAll(rows, &owners))
+
+	rows, err = db.Query("scan strict duplicate")
+	assert.NoError(t, err)
+	var players []Player
+	assert.NoError(t, Lenient.ScanAll(rows, &players))
+	assert.Equal(t, []Player{{ID: 1, Name: "a", Active: true}}, players)
+
+	rows, err = db.Query("scan strict duplicate")
+	assert.NoError(t, err)
+	err = Strict.ScanAll(rows, &players)
+	assert.Equal(t, &ScanError{Type: reflect.TypeOf(Player{}), Fields: []string{"Nick", "Score"}, Duplicates: []string{"id", "name"}}, err)
+	assert.EqualError(t, err, "scan tyr.Player: fields without column: Nick, Score; duplicate columns: id, name")
+	assert.NoError(t, rows.Close())
+
+	rows, err = db.Query("scan strict nested")
+	assert.NoError(t, err)
+	assert.EqualError(t, Strict.ScanAll(rows, &owners), "scan tyr.gameOwner: fields without column: Maker.Name")
+	assert.NoError(t, rows.Close())
+}
+
+type gameOwner struct {
+	ID    int    `json:"game_id" sql:"game_id"`
+	Code  string `json:"game_code" sql:"game_code"`
+	Owner *User  `json:"owner" sql:"u,nested"`
+	Maker User   `json:"maker" sql:"m,nested"`
+}
+
+func TestScanNested(t *testing.T) {
+	columns := []string{"game_id", "game_code", "u__id", "u__name", "m__id", "m__name"}
+	db := openFake(t, "scan nested", columns,
+		[]driver.Value{int64(1), "g1", int64(7), "sury", int64(8), "kencana"},
+		[]driver.Value{int64(2), "g2", nil, nil, int64(8), "kencana"},
+	)
+	defer db.Close()
+
+	rows, err := db.Query("scan nested")
+	assert.NoError(t, err)
+	var values []gameOwner
+	assert.NoError(t, ScanAll(rows, &values))
+	assert.Equal(t, []gameOwner{
+		{ID: 1, Code: "g1", Owner: &User{ID: 7, Name: "sury"}, Maker: User{ID: 8, Name: "kencana"}},
+		{ID: 2, Code: "g2", Maker: User{ID: 8, Name: "kencana"}},
+	}, values)
+
+	assert.Equal(t, []string{"game_id", "game_code"}, modelColumns("sql", gameOwner{}))
+	fields, _, err := tagsToField("sql", gameOwner{ID: 1, Owner: &User{ID: 7}}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(fields))
+}
+
+// scanRowJSON is the former ScanRow, scanning into a map cloned into
+// the destination through JSON, kept to benchmark against.
+func scanRowJSON(rs *sql.Rows, dest interface{}) error {
+	t := reflect.TypeOf(dest).Elem()
+	columns, err := rs.Columns()
+	if err != nil {
+		return err
+	}
+	plan := colToFieldIndex(t, columns)
+	v := reflect.New(t)
+	pointers := make([]interface{}, len(columns))
+	for x := range columns {
+		f := v.Elem().FieldByIndex(plan[x])
+		switch f.Kind() {
+		case reflect.String, reflect.Bool, reflect.Float64, reflect.Float32, reflect.Int, reflect.Int32, reflect.Int64:
+			pointers[x] = f.Addr().Interface()
+			continue
+		}
+		pointers[x] = new(interface{})
+	}
+	if err := rs.Scan(pointers...); err != nil {
+		return err
+	}
+	dataMap := make(map[string]interface{}, len(columns))
+	for idx, col := range columns {
+		dataMap[col] = pointers[idx]
+	}
+	b, err := json.Marshal(&dataMap)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, dest)
+}
+
+func benchmarkScan(b *testing.B, scan func(*sql.Rows, interface{}) error) {
+	columns := []string{"id", "name", "enabled", "rate"}
+	rows := make([][]driver.Value, b.N)
+	for i := range rows {
+		rows[i] = []driver.Value{int64(i), "sury", true, int64(9)}
+	}
+	query := fmt.Sprintf("bench %p", scan)
+	db := openFake(b, query, columns, rows...)
+	defer db.Close()
+
+	rs, err := db.Query(query)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer rs.Close()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for rs.Next() {
+		var row scanned
+		if err := scan(rs, &row); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkScanRow(b *testing.B) {
+	benchmarkScan(b, ScanRow)
+}
+
+func BenchmarkScanRowJSON(b *testing.B) {
+	benchmarkScan(b, scanRowJSON)
+}
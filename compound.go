@@ -0,0 +1,74 @@
+package tyr
+
+import (
+	"fmt"
+)
+
+const (
+	UNION     string = "UNION"
+	UNIONALL  string = "UNION ALL"
+	INTERSECT string = "INTERSECT"
+	EXCEPT    string = "EXCEPT"
+)
+
+// compound is a select combined with the statement by a set operator.
+type compound struct {
+	operator string
+	query    *Query
+}
+
+// Union combines the select with the others, dropping duplicate rows.
+// The ORDER BY, LIMIT and OFFSET of the query apply to the combined
+// result and order by its output column names, e.g. OrderBy("rate").
+func (s *Query) Union(others ...*Query) *Query {
+	return s.compound(UNION, others)
+}
+
+// UnionAll combines the select with the others, keeping every row.
+func (s *Query) UnionAll(others ...*Query) *Query {
+	return s.compound(UNIONALL, others)
+}
+
+// Intersect keeps the rows returned by the select and every other.
+func (s *Query) Intersect(others ...*Query) *Query {
+	return s.compound(INTERSECT, others)
+}
+
+// Except keeps the rows of the select not returned by the others.
+func (s *Query) Except(others ...*Query) *Query {
+	return s.compound(EXCEPT, others)
+}
+
+func (s *Query) compound(operator string, others []*Query) *Query {
+	q := s.clone()
+	if !q.raw.isSelect() {
+		q.raw.addError(fmt.Errorf("%s requires a select built by From", operator))
+		return q
+	}
+	for _, other := range others {
+		if other == nil {
+			q.raw.addError(fmt.Errorf("%s requires a select built by From", operator))
+			return q
+		}
+		q.raw.compounds = append(q.raw.compounds, compound{operator: operator, query: other})
+	}
+	return q
+}
+
+// operand writes a select combined into the statement, an operand with
+// its own ORDER BY, LIMIT or WITH clause is wrapped in parentheses.
+func (r *SQL) operand(st *statement, c compound) {
+	if c.query.raw == nil || !c.query.raw.isSelect() {
+		st.errs = append(st.errs, fmt.Errorf("%s requires a select built by From", c.operator))
+		return
+	}
+	sub := c.query.raw
+	if len(sub.orderBy) > 0 || len(sub.keyset) > 0 || len(sub.ctes) > 0 || c.query.Rows > 0 || c.query.Offset > 0 {
+		r.subquery(st, c.query)
+		return
+	}
+	q := c.query.clone()
+	q.dialect = r.query.Dialect()
+	q.arrays = r.query.arrays
+	q.raw.render(st, true)
+}
@@ -0,0 +1,35 @@
+package tyr
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRawQuery_Compound(t *testing.T) {
+	rated := Build().From(Game{}, "g").Select("g.game_id", "g.rate").Where(Gt("g.rate", 8))
+	owned := Build().From(Game{}, "g").Select("g.game_id", "g.rate").Where(Eq("g.user_id", 3))
+
+	query, args := rated.Union(owned).OrderBy(Desc("rate")).Limit(10).Page(2).ToSQL()
+	assert.Equal(t, "SELECT g.game_id, g.rate FROM ref_game g WHERE g.rate > $1 UNION SELECT g.game_id, g.rate FROM ref_game g WHERE g.user_id = $2 ORDER BY rate DESC LIMIT 10 OFFSET 10", query)
+	assert.Equal(t, []interface{}{8, 3}, args)
+
+	query, args = rated.UnionAll(owned).Except(owned.Where(Lt("g.rate", 2)).Limit(5)).ToSQL()
+	assert.Equal(t, "SELECT g.game_id, g.rate FROM ref_game g WHERE g.rate > $1 UNION ALL SELECT g.game_id, g.rate FROM ref_game g WHERE g.user_id = $2 EXCEPT (SELECT g.game_id, g.rate FROM ref_game g WHERE g.user_id = $3 AND g.rate < $4 LIMIT 5 OFFSET 0) LIMIT 100 OFFSET 0", query)
+	assert.Equal(t, []interface{}{8, 3, 3, 2}, args)
+
+	query, args = rated.SetDialect(MYSQL).Intersect(owned).ToSQL()
+	assert.Equal(t, "SELECT g.game_id, g.rate FROM ref_game g WHERE g.rate > ? INTERSECT SELECT g.game_id, g.rate FROM ref_game g WHERE g.user_id = ? LIMIT 0, 100", query)
+	assert.Equal(t, []interface{}{8, 3}, args)
+
+	query, _ = rated.Union(owned).CountQuery().ToSQL()
+	assert.Equal(t, "SELECT COUNT(*) FROM (SELECT g.game_id, g.rate FROM ref_game g WHERE g.rate > $1 UNION SELECT g.game_id, g.rate FROM ref_game g WHERE g.user_id = $2) AS counted", query)
+
+	query, _ = rated.ToSQL()
+	assert.Equal(t, "SELECT g.game_id, g.rate FROM ref_game g WHERE g.rate > $1 LIMIT 100 OFFSET 0", query)
+
+	_, _, err := rated.Union(Build().Delete(Game{ID: 1})).Build()
+	assert.Error(t, err)
+	_, _, err = Build().Delete(Game{ID: 1}).Union(rated).Build()
+	assert.Error(t, err)
+}
@@ -0,0 +1,103 @@
+package tyr
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// derived wraps a select rendered without its ORDER BY and LIMIT.
+type derived struct {
+	head, tail string
+}
+
+// CountQuery derives the statement counting the rows of a select built
+// by From, keeping its joins and conditions but not its order, limit,
+// offset, row locking or keyset cursor. A grouped or combined select, or
+// one with a projection set by Select, e.g. DISTINCT g.game_code, is
+// counted as a subquery.
+func (s *Query) CountQuery() *Query {
+	projection := s.raw != nil && len(s.raw.columns) > 0
+	q := s.derive(projection)
+	if projection || len(q.raw.groupBy) > 0 || len(q.raw.compounds) > 0 {
+		q.raw.derived = &derived{head: "SELECT COUNT(*) FROM (", tail: ") AS counted"}
+		return q
+	}
+	q.raw.columns = []string{"COUNT(*)"}
+	q.raw.derived = &derived{}
+	return q
+}
+
+// ExistsQuery derives the statement reporting whether a select built by
+// From matches any row.
+func (s *Query) ExistsQuery() *Query {
+	q := s.derive(false)
+	q.raw.derived = &derived{head: "SELECT EXISTS (", tail: ")"}
+	return q
+}
+
+// derive strips the select down to its rows, keeping the projection
+// set by Select when asked to.
+func (s *Query) derive(projection bool) *Query {
+	q := s.clone()
+	if !q.raw.isSelect() {
+		q.raw.addError(fmt.Errorf("count and exists require a select built by From"))
+	}
+	if projection {
+		q.raw.autoColumns = false
+	} else if len(q.raw.compounds) < 1 {
+		// the columns of a combined select must match its operands
+		q.raw.columns = []string{"1"}
+		q.raw.autoColumns = false
+	}
+	q.raw.orderBy = nil
+	q.raw.lock = nil
+	return q
+}
+
+// Count runs the CountQuery of the select on the database.
+func (s *Query) Count(ctx context.Context, db Factory) (count int64, err error) {
+	query, args, err := s.on(db).CountQuery().Build()
+	if err != nil {
+		return 0, err
+	}
+	err = db.QueryRowCtx(ctx, func(rs *sql.Row) error {
+		return rs.Scan(&count)
+	}, query, args...)
+	return count, err
+}
+
+// Exists runs the ExistsQuery of the select on the database.
+func (s *Query) Exists(ctx context.Context, db Factory) (exists bool, err error) {
+	query, args, err := s.on(db).ExistsQuery().Build()
+	if err != nil {
+		return false, err
+	}
+	err = db.QueryRowCtx(ctx, func(rs *sql.Row) error {
+		return rs.Scan(&exists)
+	}, query, args...)
+	return exists, err
+}
+
+// on renders the query for the dialect of the database, e.g. *DB, and
+// fails to build when the query is set to another dialect. The query is
+// kept as is when the database does not know its dialect.
+func (s *Query) on(db Factory) *Query {
+	d, ok := db.(interface{ Dialect() (Dialect, error) })
+	if !ok {
+		return s
+	}
+	dialect, err := d.Dialect()
+	if err != nil {
+		return s
+	}
+	if s.dialect == nil {
+		q := s.clone()
+		q.dialect = dialect
+		return q
+	}
+	if s.dialect.Name() != dialect.Name() {
+		return s.AddError(fmt.Errorf("query is built for %s, the database is %s", s.dialect.Name(), dialect.Name()))
+	}
+	return s
+}
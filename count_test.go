@@ -0,0 +1,65 @@
+package tyr
+
+import (
+	"context"
+	"database/sql/driver"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRawQuery_CountQuery(t *testing.T) {
+	list := Build().
+		From(Game{Enabled: true}, "g").
+		Join(User{}, "u", On("u.id", "g.user_id")).
+		Where(Gt("g.rate", 10)).
+		OrderBy(Desc("g.rate")).
+		Limit(20).
+		Page(2)
+
+	query, args := list.CountQuery().ToSQL()
+	assert.Equal(t, "SELECT COUNT(*) FROM ref_game g JOIN ref_user u ON u.id = g.user_id WHERE g.enabled = $1 AND g.rate > $2", query)
+	assert.Equal(t, []interface{}{true, 10}, args)
+
+	query, _ = list.ExistsQuery().ToSQL()
+	assert.Equal(t, "SELECT EXISTS (SELECT 1 FROM ref_game g JOIN ref_user u ON u.id = g.user_id WHERE g.enabled = $1 AND g.rate > $2)", query)
+
+	query, _ = list.ToSQL()
+	assert.Equal(t, "SELECT g.*, u.* FROM ref_game g JOIN ref_user u ON u.id = g.user_id WHERE g.enabled = $1 AND g.rate > $2 ORDER BY g.rate DESC LIMIT 20 OFFSET 20", query)
+
+	query, _ = Build().From(Game{}, "g").GroupBy("g.game_code").Having("COUNT(*) > ?", 1).CountQuery().ToSQL()
+	assert.Equal(t, "SELECT COUNT(*) FROM (SELECT 1 FROM ref_game g GROUP BY g.game_code HAVING COUNT(*) > $1) AS counted", query)
+
+	cursor, _ := EncodeCursor(5, 9)
+	query, args = Build().From(Game{}, "g").Keyset(cursor, Desc("g.rate"), Desc("g.game_id")).CountQuery().ToSQL()
+	assert.Equal(t, "SELECT COUNT(*) FROM ref_game g", query)
+	assert.Empty(t, args)
+
+	query, _ = Build().From(Game{}, "g").Select("DISTINCT g.game_code").OrderBy("g.game_code").CountQuery().ToSQL()
+	assert.Equal(t, "SELECT COUNT(*) FROM (SELECT DISTINCT g.game_code FROM ref_game g) AS counted", query)
+
+	query, _ = Build().From(Game{}, "g").Select("DISTINCT g.game_code").ExistsQuery().ToSQL()
+	assert.Equal(t, "SELECT EXISTS (SELECT 1 FROM ref_game g)", query)
+
+	_, _, err := Build().Delete(Game{ID: 1}).CountQuery().Build()
+	assert.Error(t, err)
+}
+
+func TestCountDialect(t *testing.T) {
+	ctx := context.Background()
+	slave := openFake(t, "SELECT COUNT(*) FROM ref_game g WHERE g.game_code = ?", []string{"count"}, []driver.Value{int64(3)})
+	defer slave.Close()
+	defer openFake(t, "SELECT EXISTS (SELECT 1 FROM ref_game g WHERE g.game_code = ?)", []string{"exists"}, []driver.Value{true}).Close()
+
+	list := Build().From(Game{Code: "x"}, "g")
+	db := &DB{Slave: slave, Driver: MYSQL}
+	count, err := list.Count(ctx, db)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), count)
+	exists, err := list.Exists(ctx, NewTracerConn(db))
+	assert.NoError(t, err)
+	assert.True(t, exists)
+
+	_, err = list.SetDialect(POSTGRES).Count(ctx, db)
+	assert.EqualError(t, err, "query is built for postgres, the database is mysql")
+}
@@ -16,6 +16,7 @@ import (
 const (
 	POSTGRES string = "postgres"
 	MYSQL    string = "mysql"
+	SQLITE   string = "sqlite3"
 )
 
 type TxArgs struct {
@@ -38,12 +39,30 @@ type Factory interface {
 	PrepareContext(ctx context.Context, query string) (stmt *sql.Stmt, err error)
 }
 
+// TxQuerier runs a select in a transaction, e.g. one locking the rows,
+// implemented by *DB and the tracer connection apart from Factory.
+type TxQuerier interface {
+	TxQueryCtx(ctx context.Context, tx *sql.Tx, fn func(rs *sql.Rows) error, query string, args ...interface{}) error
+}
+
+// Returner scans the columns of a RETURNING clause, implemented by *DB
+// and the tracer connection apart from Factory.
+type Returner interface {
+	TxExecContextReturning(ctx context.Context, tx *sql.Tx, dest interface{}, query string, args ...interface{}) error
+}
+
 type DB struct {
 	Master     *sql.DB
 	Slave      *sql.DB
 	RetryCount int
 	Timeout    int
 	Concurrent int
+	Driver     string
+}
+
+// Dialect returns the dialect of the master connection driver.
+func (r *DB) Dialect() (Dialect, error) {
+	return GetDialect(r.Driver)
 }
 
 func (r *DB) Ping() error {
@@ -108,6 +127,13 @@ func (r *DB) QueryRowCtx(ctx context.Context, fn func(rs *sql.Row) error, query
 		mimir.Field("args", args),
 	)
 
+	if isLocking(query) {
+		logger.With(
+			mimir.Field("query", query),
+		).Error("event QueryRowCtx: row locking outside of a transaction")
+
+		return fmt.Errorf("event QueryRowCtx: row locking requires a transaction, use TxQueryCtx")
+	}
 	if r.Slave == nil {
 		logger.With(
 			mimir.Field("query", query),
@@ -144,6 +170,13 @@ func (r *DB) QueryCtx(ctx context.Context, fn func(rs *sql.Rows) error, query st
 		mimir.Field("query", query),
 		mimir.Field("args", args),
 	)
+	if isLocking(query) {
+		logger.With(
+			mimir.Field("query", query),
+		).Error("event QueryCtx: row locking outside of a transaction")
+
+		return fmt.Errorf("event QueryCtx: row locking requires a transaction, use TxQueryCtx")
+	}
 	if r.Slave == nil {
 		logger.With(
 			mimir.Field("query", query),
@@ -188,6 +221,60 @@ func (r *DB) QueryCtx(ctx context.Context, fn func(rs *sql.Rows) error, query st
 	return nil
 }
 
+// TxQueryCtx runs the select in the transaction on the master, which
+// holds the row locks taken by FOR UPDATE or FOR SHARE until it ends.
+func (r *DB) TxQueryCtx(ctx context.Context, tx *sql.Tx, fn func(rs *sql.Rows) error, query string, args ...interface{}) (err error) {
+	logger := mimir.For(ctx)
+	logger.Info("TxQueryCtx Running...",
+		mimir.Field("query", query),
+		mimir.Field("args", args),
+	)
+	if tx == nil {
+		logger.With(
+			mimir.Field("query", query),
+			mimir.Field("args", args),
+		).Error("event TxQueryCtx: the transaction is nil")
+
+		return fmt.Errorf("event TxQueryCtx: cannot access your transaction")
+	}
+
+	rs, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		logger.Warn("event TxQueryCtx: query failed",
+			mimir.Field("query", query),
+			mimir.Field("args", args),
+		)
+
+		return err
+	}
+
+	defer func() {
+		if errClose := rs.Close(); err == nil {
+			err = errClose
+		}
+	}()
+
+	if err := fn(rs); err != nil {
+		if err == sql.ErrNoRows {
+			logger.Warn("event TxQueryCtx: result not found",
+				mimir.Field("query", query),
+				mimir.Field("args", args),
+			)
+
+			return nil
+		}
+
+		logger.With(
+			mimir.Field("query", query),
+			mimir.Field("args", args),
+		).Error("event TxQueryCtx: query row failed")
+
+		return err
+	}
+
+	return nil
+}
+
 func (r *DB) TxExecContextWithID(ctx context.Context, tx *sql.Tx, query string, args ...interface{}) (ids interface{}, err error) {
 	logger := mimir.For(ctx)
 	logger.Info("TxExecContextWithID Running...",
@@ -195,50 +282,146 @@ func (r *DB) TxExecContextWithID(ctx context.Context, tx *sql.Tx, query string,
 		mimir.Field("args", args),
 	)
 
-	if strings.Contains(query, "RETURNING") {
-		stmt, er := tx.PrepareContext(ctx, query)
-		if er != nil {
+	// the id is returned by RETURNING when the dialect supports it,
+	// lib/pq does not implement LastInsertId. Without a Driver the
+	// query decides, as it did before the dialects.
+	returning := strings.Contains(query, "RETURNING")
+	var er error
+	if dialect, err := r.Dialect(); err == nil {
+		if dialect.SupportsReturning() && !returning {
+			er = fmt.Errorf("query has no RETURNING syntax, required by %s to return the id", dialect.Name())
+		}
+		returning = dialect.SupportsReturning()
+	}
+	if er != nil {
+		logger.With(
+			mimir.Field("error", er.Error()),
+			mimir.Field("query", query),
+			mimir.Field("args", args),
+		).Error("TxExecContextWithID: Dialect")
+
+		return nil, er
+	}
+
+	stmt, er := tx.PrepareContext(ctx, query)
+	if er != nil {
+		logger.With(
+			mimir.Field("error", er.Error()),
+			mimir.Field("query", query),
+			mimir.Field("args", args),
+		).Error("TxExecContextWithID: PrepareContext")
+
+		return nil, er
+	}
+
+	if returning {
+		if er := stmt.QueryRowContext(ctx, args...).Scan(&ids); er != nil {
 			logger.With(
 				mimir.Field("error", er.Error()),
 				mimir.Field("query", query),
 				mimir.Field("args", args),
-			).Error("TxExecContextWithID: PrepareContext")
+			).Error("TxExecContextWithID: QueryRowContext")
 
+			_ = stmt.Close()
 			return nil, er
 		}
-
-		if er := stmt.QueryRowContext(ctx, args...).Scan(&ids); er != nil {
+	} else {
+		// dialects without RETURNING report the id through the result
+		result, er := stmt.ExecContext(ctx, args...)
+		if er != nil {
 			logger.With(
 				mimir.Field("error", er.Error()),
 				mimir.Field("query", query),
 				mimir.Field("args", args),
-			).Error("TxExecContextWithID: QueryRowContext")
+			).Error("TxExecContextWithID: ExecContext")
 
+			_ = stmt.Close()
 			return nil, er
 		}
 
-		if errStmt := stmt.Close(); errStmt != nil {
+		id, er := result.LastInsertId()
+		if er != nil {
 			logger.With(
-				mimir.Field("error", errStmt.Error()),
+				mimir.Field("error", er.Error()),
 				mimir.Field("query", query),
 				mimir.Field("args", args),
-			).Error("TxExecContextWithID: Statement Close")
+			).Error("TxExecContextWithID: LastInsertId")
 
-			return nil, errStmt
+			_ = stmt.Close()
+			return nil, er
 		}
+		ids = id
+	}
 
-		return ids, nil
+	if errStmt := stmt.Close(); errStmt != nil {
+		logger.With(
+			mimir.Field("error", errStmt.Error()),
+			mimir.Field("query", query),
+			mimir.Field("args", args),
+		).Error("TxExecContextWithID: Statement Close")
+
+		return nil, errStmt
 	}
 
-	err = fmt.Errorf("query has no RETUNING id syntax")
+	return ids, nil
+}
 
-	logger.With(
-		mimir.Field("error", err.Error()),
+// TxExecContextReturning executes a statement with a RETURNING clause
+// and scans the returned columns into the dest struct.
+func (r *DB) TxExecContextReturning(ctx context.Context, tx *sql.Tx, dest interface{}, query string, args ...interface{}) (err error) {
+	logger := mimir.For(ctx)
+	logger.Info("TxExecContextReturning Running...",
 		mimir.Field("query", query),
 		mimir.Field("args", args),
-	).Error("TxExecContextWithID:")
+	)
+
+	if !strings.Contains(query, "RETURNING") {
+		err = fmt.Errorf("query has no RETURNING syntax")
+
+		logger.With(
+			mimir.Field("error", err.Error()),
+			mimir.Field("query", query),
+			mimir.Field("args", args),
+		).Error("TxExecContextReturning:")
+
+		return err
+	}
+
+	rs, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		logger.With(
+			mimir.Field("error", err.Error()),
+			mimir.Field("query", query),
+			mimir.Field("args", args),
+		).Error("TxExecContextReturning: QueryContext")
+
+		return err
+	}
+
+	defer func() {
+		if errClose := rs.Close(); errClose != nil && err == nil {
+			err = errClose
+		}
+	}()
+
+	if !rs.Next() {
+		if err = rs.Err(); err != nil {
+			return err
+		}
+		return sql.ErrNoRows
+	}
 
-	return nil, err
+	if err = ScanRow(rs, dest); err != nil {
+		logger.With(
+			mimir.Field("error", err.Error()),
+			mimir.Field("query", query),
+			mimir.Field("args", args),
+		).Error("TxExecContextReturning: ScanRow")
+
+		return err
+	}
+
+	return nil
 }
 
 func (r *DB) TxExecContext(ctx context.Context, tx *sql.Tx, query string, args ...interface{}) (affected int64, err error) {
@@ -331,15 +514,14 @@ func New(master, slave SqlConn) (*DB, error) {
 	// Master Connection
 	m, err := sql.Open(master.Driver, master.ConnStr)
 	if err != nil {
-		//mimir.Error(err.Error())
-		panic(fmt.Errorf("cannot access your db master connection").Error())
+		return nil, fmt.Errorf("cannot access your db master connection: %v", err)
 	}
 
 	// Slave Connection
 	s, err := sql.Open(slave.Driver, slave.ConnStr)
 	if err != nil {
-		//mimir.Error(err.Error())
-		panic(fmt.Errorf("cannot access your db slave connection").Error())
+		_ = m.Close()
+		return nil, fmt.Errorf("cannot access your db slave connection: %v", err)
 	}
 
 	return &DB{
@@ -348,6 +530,7 @@ func New(master, slave SqlConn) (*DB, error) {
 		master.RetryCount,
 		master.Timeout,
 		master.Concurrent,
+		master.Driver,
 	}, nil
 }
 
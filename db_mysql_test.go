@@ -19,6 +19,12 @@ const (
 	mysqlSchema = `CREATE TABLE IF NOT EXISTS users (
 id integer NOT NULL, 
 name varchar(255) NOT NULL
+);`
+	memberSchema = `CREATE TABLE IF NOT EXISTS ref_member (
+id integer NOT NULL AUTO_INCREMENT PRIMARY KEY,
+name varchar(255) NOT NULL,
+create_date datetime NOT NULL,
+write_date datetime NOT NULL
 );`
 )
 
@@ -204,6 +210,25 @@ func (s *ConnMYSuite) TestWithTransaction() {
 	assert.NoError(t, err)
 }
 
+func (s *ConnMYSuite) TestTxExecContextWithLastInsertID() {
+	t := s.T()
+	ctx := s.GetContext()
+	query, args := Build().SetDialect(MYSQL).Insert(&Member{ID: 1005, Name: "TEST LastInsertId"}).ToSQL()
+	assert.NotContains(t, query, "RETURNING")
+	err := s.DB.WithTransaction(ctx, func(ctxSpan context.Context, tx *sql.Tx) error {
+		if _, err := s.DB.ExecContext(ctxSpan, memberSchema); err != nil {
+			return err
+		}
+		ids, err := s.DB.TxExecContextWithID(ctxSpan, tx, query, args...)
+		if err != nil {
+			return err
+		}
+		assert.IsType(t, int64(0), ids)
+		return s.DB.TxCommit(ctxSpan, tx)
+	})
+	assert.NoError(t, err)
+}
+
 func (s *ConnMYSuite) TestWithTransactionFail() {
 	t := s.T()
 	ctx := s.GetContext()
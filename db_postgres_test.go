@@ -209,6 +209,74 @@ func (s *ConnPGSuite) TestWithTransaction() {
 	assert.NoError(t, err)
 }
 
+func (s *ConnPGSuite) TestTxExecContextReturning() {
+	t := s.T()
+	ctx := s.GetContext()
+	query := `INSERT INTO users (id, name) VALUES ($1, $2) RETURNING id, name`
+	var row struct {
+		ID   int    `json:"id" sql:"id"`
+		Name string `json:"name" sql:"name"`
+	}
+	returner, ok := s.DB.(Returner)
+	assert.True(t, ok)
+	err := s.DB.WithTransaction(ctx, func(ctxSpan context.Context, tx *sql.Tx) error {
+		if err := returner.TxExecContextReturning(ctxSpan, tx, &row, query, 1004, "TEST Returning"); err != nil {
+			return err
+		}
+		return s.DB.TxCommit(ctxSpan, tx)
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 1004, row.ID)
+	assert.Equal(t, "TEST Returning", row.Name)
+}
+
+type pgUser struct {
+	ID   int    `sql:"id"`
+	Name string `sql:"name"`
+}
+
+func (pgUser) TableName() string {
+	return "users"
+}
+
+func (s *ConnPGSuite) TestCountExists() {
+	t := s.T()
+	ctx := s.GetContext()
+	_, err := s.DB.ExecContext(ctx, `INSERT INTO users (id, name) VALUES ($1, $2), ($3, $4)`, 1005, "TEST Count", 1006, "TEST Count")
+	assert.NoError(t, err)
+
+	list := Build().From(pgUser{}, "u").Where(Eq("u.name", "TEST Count")).OrderBy(Asc("u.id")).Limit(1)
+	count, err := list.Count(ctx, s.DB)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+
+	exists, err := list.Exists(ctx, s.DB)
+	assert.NoError(t, err)
+	assert.True(t, exists)
+
+	exists, err = list.Where(Gt("u.id", 1006)).Exists(ctx, s.DB)
+	assert.NoError(t, err)
+	assert.False(t, exists)
+}
+
+func (s *ConnPGSuite) TestTxQueryCtx() {
+	t := s.T()
+	ctx := s.GetContext()
+	query, args := Build().From(pgUser{}, "u").Where(Gte("u.id", 1000)).OrderBy(Asc("u.id")).Limit(1).ForUpdate().SkipLocked().ToSQL()
+	querier, ok := s.DB.(TxQuerier)
+	assert.True(t, ok)
+	err := s.DB.WithTransaction(ctx, func(ctxSpan context.Context, tx *sql.Tx) error {
+		if err := querier.TxQueryCtx(ctxSpan, tx, func(rs *sql.Rows) error {
+			return rs.Err()
+		}, query, args...); err != nil {
+			return err
+		}
+		return s.DB.TxCommit(ctxSpan, tx)
+	})
+	assert.NoError(t, err)
+	assert.Error(t, s.DB.QueryCtx(ctx, func(rs *sql.Rows) error { return nil }, query, args...))
+}
+
 func (s *ConnPGSuite) TestWithTransactionFail() {
 	t := s.T()
 	ctx := s.GetContext()
@@ -254,6 +322,12 @@ func TestMainPGSuite(t *testing.T) {
 	suite.Run(t, new(ConnPGSuite))
 }
 
+func TestNewOpenError(t *testing.T) {
+	db, err := NewNoSlave(SqlConn{Driver: "unknown", ConnStr: ""})
+	assert.Error(t, err)
+	assert.Nil(t, db)
+}
+
 type ConnectionSuite interface {
 	T() *testing.T
 	GetResource() *dockertest.Resource
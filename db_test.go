@@ -0,0 +1,59 @@
+package tyr
+
+import (
+	"context"
+	"database/sql/driver"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTxExecContextWithID(t *testing.T) {
+	ctx := context.Background()
+	returning := "INSERT INTO players (name) VALUES ($1) RETURNING id"
+	master := openFake(t, returning, []string{"id"}, []driver.Value{int64(9)})
+	defer master.Close()
+	tx, err := master.Begin()
+	assert.NoError(t, err)
+	defer func() { _ = tx.Rollback() }()
+
+	db := &DB{Master: master, Driver: POSTGRES}
+	ids, err := db.TxExecContextWithID(ctx, tx, returning, "sury")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(9), ids)
+
+	_, err = db.TxExecContextWithID(ctx, tx, "INSERT INTO players (name) VALUES ($1)", "sury")
+	assert.EqualError(t, err, "query has no RETURNING syntax, required by postgres to return the id")
+
+	db = &DB{Master: master, Driver: MYSQL}
+	ids, err = db.TxExecContextWithID(ctx, tx, "INSERT INTO players (name) VALUES (?)", "sury")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(7), ids)
+
+	db = &DB{Master: master}
+	ids, err = db.TxExecContextWithID(ctx, tx, returning, "sury")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(9), ids)
+
+	ids, err = db.TxExecContextWithID(ctx, tx, "INSERT INTO players (name) VALUES ($1)", "sury")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(7), ids)
+}
+
+func TestReturner(t *testing.T) {
+	var db Factory = &DB{}
+	_, ok := db.(Returner)
+	assert.True(t, ok)
+
+	_, ok = NewTracerConn(&DB{}).(Returner)
+	assert.True(t, ok)
+}
+
+func TestTxQuerier(t *testing.T) {
+	var db Factory = &DB{}
+	_, ok := db.(TxQuerier)
+	assert.True(t, ok)
+
+	_, ok = NewTracerConn(&DB{}).(TxQuerier)
+	assert.True(t, ok)
+}
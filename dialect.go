@@ -0,0 +1,181 @@
+package tyr
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Dialect renders the backend specific parts of a statement.
+type Dialect interface {
+	Name() string
+	Placeholder(n int) string
+	Quote(identifier string) string
+	LimitOffset(limit, offset int) string
+	SupportsReturning() bool
+	SupportsNullsOrder() bool
+	SupportsRowLocking() bool
+	Now() string
+	OnConflict(target, update []string) string
+}
+
+var dialects = map[string]Dialect{
+	POSTGRES: postgresDialect{},
+	MYSQL:    mysqlDialect{},
+	SQLITE:   sqliteDialect{},
+}
+
+// GetDialect returns the dialect registered for the driver name,
+// e.g. POSTGRES, MYSQL or SQLITE.
+func GetDialect(driver string) (Dialect, error) {
+	if d, ok := dialects[driver]; ok {
+		return d, nil
+	}
+	return nil, fmt.Errorf("dialect for driver %q is not supported", driver)
+}
+
+type postgresDialect struct{}
+
+func (postgresDialect) Name() string {
+	return POSTGRES
+}
+
+func (postgresDialect) Placeholder(n int) string {
+	return "$" + strconv.Itoa(n)
+}
+
+func (postgresDialect) Quote(identifier string) string {
+	return `"` + strings.Replace(identifier, `"`, `""`, -1) + `"`
+}
+
+func (postgresDialect) LimitOffset(limit, offset int) string {
+	return fmt.Sprintf("LIMIT %d OFFSET %d", limit, offset)
+}
+
+func (postgresDialect) SupportsReturning() bool {
+	return true
+}
+
+func (postgresDialect) SupportsNullsOrder() bool {
+	return true
+}
+
+func (postgresDialect) SupportsRowLocking() bool {
+	return true
+}
+
+func (postgresDialect) Now() string {
+	return "now()"
+}
+
+func (postgresDialect) OnConflict(target, update []string) string {
+	return onConflict(target, update, "EXCLUDED")
+}
+
+type mysqlDialect struct{}
+
+func (mysqlDialect) Name() string {
+	return MYSQL
+}
+
+func (mysqlDialect) Placeholder(int) string {
+	return "?"
+}
+
+func (mysqlDialect) Quote(identifier string) string {
+	return "`" + strings.Replace(identifier, "`", "``", -1) + "`"
+}
+
+func (mysqlDialect) LimitOffset(limit, offset int) string {
+	return fmt.Sprintf("LIMIT %d, %d", offset, limit)
+}
+
+func (mysqlDialect) SupportsReturning() bool {
+	return false
+}
+
+func (mysqlDialect) SupportsNullsOrder() bool {
+	return false
+}
+
+func (mysqlDialect) SupportsRowLocking() bool {
+	return true
+}
+
+func (mysqlDialect) Now() string {
+	return "NOW()"
+}
+
+func (mysqlDialect) OnConflict(target, update []string) string {
+	columns := make([]string, 0)
+	for _, column := range update {
+		columns = append(columns, fmt.Sprintf("%s = VALUES(%s)", column, column))
+	}
+	if len(columns) < 1 && len(target) > 0 {
+		// no-op assignment keeps the existing row untouched
+		columns = append(columns, fmt.Sprintf("%s = %s", target[0], target[0]))
+	}
+	return "ON DUPLICATE KEY UPDATE " + strings.Join(columns, ", ")
+}
+
+type sqliteDialect struct{}
+
+func (sqliteDialect) Name() string {
+	return SQLITE
+}
+
+func (sqliteDialect) Placeholder(int) string {
+	return "?"
+}
+
+func (sqliteDialect) Quote(identifier string) string {
+	return `"` + strings.Replace(identifier, `"`, `""`, -1) + `"`
+}
+
+func (sqliteDialect) LimitOffset(limit, offset int) string {
+	return fmt.Sprintf("LIMIT %d OFFSET %d", limit, offset)
+}
+
+func (sqliteDialect) SupportsReturning() bool {
+	return true
+}
+
+func (sqliteDialect) SupportsNullsOrder() bool {
+	return true
+}
+
+func (sqliteDialect) SupportsRowLocking() bool {
+	return false
+}
+
+func (sqliteDialect) Now() string {
+	return "CURRENT_TIMESTAMP"
+}
+
+func (sqliteDialect) OnConflict(target, update []string) string {
+	return onConflict(target, update, "excluded")
+}
+
+// onConflict renders the ON CONFLICT clause shared by Postgres and SQLite,
+// an empty update list renders DO NOTHING.
+func onConflict(target, update []string, excluded string) string {
+	var buff strings.Builder
+	buff.WriteString("ON CONFLICT")
+	if len(target) > 0 {
+		buff.WriteString(" (")
+		buff.WriteString(strings.Join(target, ", "))
+		buff.WriteString(")")
+	}
+	if len(update) < 1 {
+		buff.WriteString(" DO NOTHING")
+		return buff.String()
+	}
+	buff.WriteString(" DO UPDATE SET ")
+	for i, column := range update {
+		if i > 0 {
+			buff.WriteString(", ")
+		}
+		buff.WriteString(fmt.Sprintf("%s = %s.%s", column, excluded, column))
+	}
+	return buff.String()
+}
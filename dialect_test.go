@@ -0,0 +1,37 @@
+package tyr
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetDialect(t *testing.T) {
+	for _, tt := range []struct {
+		driver      string
+		placeholder string
+		quote       string
+		limit       string
+	}{
+		{POSTGRES, "$3", `"user"`, "LIMIT 10 OFFSET 20"},
+		{MYSQL, "?", "`user`", "LIMIT 20, 10"},
+		{SQLITE, "?", `"user"`, "LIMIT 10 OFFSET 20"},
+	} {
+		d, err := GetDialect(tt.driver)
+		assert.NoError(t, err)
+		assert.Equal(t, tt.driver, d.Name())
+		assert.Equal(t, tt.placeholder, d.Placeholder(3))
+		assert.Equal(t, tt.quote, d.Quote("user"))
+		assert.Equal(t, tt.limit, d.LimitOffset(10, 20))
+	}
+
+	_, err := GetDialect("oracle")
+	assert.Error(t, err)
+}
+
+func TestDialectQuoteEscape(t *testing.T) {
+	pg, _ := GetDialect(POSTGRES)
+	my, _ := GetDialect(MYSQL)
+	assert.Equal(t, `"a""b"`, pg.Quote(`a"b`))
+	assert.Equal(t, "`a``b`", my.Quote("a`b"))
+}
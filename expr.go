@@ -0,0 +1,247 @@
+package tyr
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Expr is a condition accepted by Where and Having, rendered with ?
+// placeholders which are numbered when the statement is built.
+type Expr interface {
+	Expr(dialect Dialect) (query string, args []interface{})
+}
+
+type compare struct {
+	column   string
+	operator string
+	value    interface{}
+}
+
+// Expr renders Eq and Neq against a nil value as IS NULL and IS NOT NULL,
+// a comparison with NULL matches no rows.
+func (c compare) Expr(dialect Dialect) (string, []interface{}) {
+	if (c.operator == "=" || c.operator == "<>") && nullValue(c.value) {
+		return isNull{column: c.column, not: c.operator == "<>"}.Expr(dialect)
+	}
+	return fmt.Sprintf("%s %s ?", c.column, c.operator), []interface{}{c.value}
+}
+
+// nullValue reports whether the value is bound as NULL: nil, a nil
+// pointer or a Valuer holding no value.
+func nullValue(value interface{}) bool {
+	if value == nil {
+		return true
+	}
+	if v := reflect.ValueOf(value); v.Kind() == reflect.Ptr && v.IsNil() {
+		return true
+	}
+	if valuer, ok := value.(driver.Valuer); ok {
+		v, err := valuer.Value()
+		return err == nil && v == nil
+	}
+	return false
+}
+
+func Eq(column string, value interface{}) Expr {
+	return compare{column, "=", value}
+}
+
+func Neq(column string, value interface{}) Expr {
+	return compare{column, "<>", value}
+}
+
+func Gt(column string, value interface{}) Expr {
+	return compare{column, ">", value}
+}
+
+func Gte(column string, value interface{}) Expr {
+	return compare{column, ">=", value}
+}
+
+func Lt(column string, value interface{}) Expr {
+	return compare{column, "<", value}
+}
+
+func Lte(column string, value interface{}) Expr {
+	return compare{column, "<=", value}
+}
+
+func Like(column string, pattern string) Expr {
+	return compare{column, "LIKE", pattern}
+}
+
+type columns struct {
+	left, right string
+}
+
+// On compares two columns, e.g. On("u.id", "g.user_id") for a join.
+func On(left, right string) Expr {
+	return columns{left, right}
+}
+
+func (c columns) Expr(Dialect) (string, []interface{}) {
+	return fmt.Sprintf("%s = %s", c.left, c.right), nil
+}
+
+type ilike struct {
+	column  string
+	pattern string
+}
+
+// ILike matches case-insensitively, dialects without ILIKE compare
+// the lower cased column and pattern.
+func ILike(column string, pattern string) Expr {
+	return ilike{column, pattern}
+}
+
+func (l ilike) Expr(dialect Dialect) (string, []interface{}) {
+	if dialect.Name() == POSTGRES {
+		return fmt.Sprintf("%s ILIKE ?", l.column), []interface{}{l.pattern}
+	}
+	return fmt.Sprintf("LOWER(%s) LIKE LOWER(?)", l.column), []interface{}{l.pattern}
+}
+
+type between struct {
+	column   string
+	from, to interface{}
+}
+
+func Between(column string, from, to interface{}) Expr {
+	return between{column, from, to}
+}
+
+func (b between) Expr(Dialect) (string, []interface{}) {
+	return fmt.Sprintf("%s BETWEEN ? AND ?", b.column), []interface{}{b.from, b.to}
+}
+
+type isNull struct {
+	column string
+	not    bool
+}
+
+func IsNull(column string) Expr {
+	return isNull{column: column}
+}
+
+func IsNotNull(column string) Expr {
+	return isNull{column: column, not: true}
+}
+
+func (n isNull) Expr(Dialect) (string, []interface{}) {
+	if n.not {
+		return fmt.Sprintf("%s IS NOT NULL", n.column), nil
+	}
+	return fmt.Sprintf("%s IS NULL", n.column), nil
+}
+
+type in struct {
+	column string
+	values []interface{}
+}
+
+// In matches the column against the values, a single slice value is
+// expanded into its elements and a single *Query is bound as a subquery.
+// An empty list matches no rows.
+func In(column string, values ...interface{}) Expr {
+	if len(values) == 1 {
+		if sub, ok := values[0].(*Query); ok {
+			return raw{fmt.Sprintf("%s IN ?", column), []interface{}{sub}}
+		}
+		v := reflect.ValueOf(values[0])
+		if v.Kind() == reflect.Slice && v.Type().Elem().Kind() != reflect.Uint8 {
+			values = make([]interface{}, v.Len())
+			for i := 0; i < v.Len(); i++ {
+				values[i] = v.Index(i).Interface()
+			}
+		}
+	}
+	return in{column, values}
+}
+
+func (i in) Expr(Dialect) (string, []interface{}) {
+	if len(i.values) < 1 {
+		return "1 = 0", nil
+	}
+	params := make([]string, len(i.values))
+	for n := range i.values {
+		params[n] = "?"
+	}
+	return fmt.Sprintf("%s IN (%s)", i.column, strings.Join(params, ", ")), i.values
+}
+
+type not struct {
+	expr Expr
+}
+
+func Not(expr Expr) Expr {
+	return not{expr}
+}
+
+func (n not) Expr(dialect Dialect) (string, []interface{}) {
+	query, args := n.expr.Expr(dialect)
+	return fmt.Sprintf("NOT (%s)", query), args
+}
+
+// Exists matches when the select returns any row.
+func Exists(query *Query) Expr {
+	return raw{"EXISTS ?", []interface{}{query}}
+}
+
+// NotExists matches when the select returns no row.
+func NotExists(query *Query) Expr {
+	return raw{"NOT EXISTS ?", []interface{}{query}}
+}
+
+type raw struct {
+	query string
+	args  []interface{}
+}
+
+// Raw wraps a hand written condition with ? placeholders, a *Query arg
+// is bound as a subquery.
+func Raw(query string, args ...interface{}) Expr {
+	return raw{query, args}
+}
+
+func (r raw) Expr(Dialect) (string, []interface{}) {
+	return r.query, r.args
+}
+
+type group struct {
+	operator string
+	exprs    []Expr
+}
+
+// And groups the expressions with AND, a group of more than one
+// expression is wrapped in parentheses.
+func And(exprs ...Expr) Expr {
+	return group{"AND", exprs}
+}
+
+// Or groups the expressions with OR, a group of more than one
+// expression is wrapped in parentheses.
+func Or(exprs ...Expr) Expr {
+	return group{"OR", exprs}
+}
+
+func (g group) Expr(dialect Dialect) (string, []interface{}) {
+	queries := make([]string, 0, len(g.exprs))
+	args := make([]interface{}, 0)
+	for _, expr := range g.exprs {
+		query, a := expr.Expr(dialect)
+		queries = append(queries, query)
+		args = append(args, a...)
+	}
+	switch len(queries) {
+	case 0:
+		if g.operator == "OR" {
+			return "1 = 0", nil
+		}
+		return "1 = 1", nil
+	case 1:
+		return queries[0], args
+	}
+	return fmt.Sprintf("(%s)", strings.Join(queries, fmt.Sprintf(" %s ", g.operator))), args
+}
@@ -0,0 +1,51 @@
+package tyr
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpr(t *testing.T) {
+	pg, _ := GetDialect(POSTGRES)
+	my, _ := GetDialect(MYSQL)
+	for _, tt := range []struct {
+		expr    Expr
+		dialect Dialect
+		query   string
+		args    []interface{}
+	}{
+		{Eq("g.game_id", 1), pg, "g.game_id = ?", []interface{}{1}},
+		{On("u.id", "g.user_id"), pg, "u.id = g.user_id", nil},
+		{Neq("g.game_id", 1), pg, "g.game_id <> ?", []interface{}{1}},
+		{Eq("g.release", nil), pg, "g.release IS NULL", nil},
+		{Neq("g.release", (*int)(nil)), pg, "g.release IS NOT NULL", nil},
+		{Eq("g.release", NullTime{}), pg, "g.release IS NULL", nil},
+		{Gt("g.rate", 10), pg, "g.rate > ?", []interface{}{10}},
+		{Lte("g.rate", 10), pg, "g.rate <= ?", []interface{}{10}},
+		{Between("g.rate", 1, 5), pg, "g.rate BETWEEN ? AND ?", []interface{}{1, 5}},
+		{Like("g.game_title", "do%"), pg, "g.game_title LIKE ?", []interface{}{"do%"}},
+		{ILike("g.game_title", "do%"), pg, "g.game_title ILIKE ?", []interface{}{"do%"}},
+		{ILike("g.game_title", "do%"), my, "LOWER(g.game_title) LIKE LOWER(?)", []interface{}{"do%"}},
+		{IsNull("g.release"), pg, "g.release IS NULL", nil},
+		{IsNotNull("g.release"), pg, "g.release IS NOT NULL", nil},
+		{In("g.game_id", 1, 2, 3), pg, "g.game_id IN (?, ?, ?)", []interface{}{1, 2, 3}},
+		{In("g.game_id", []int{1, 2}), pg, "g.game_id IN (?, ?)", []interface{}{1, 2}},
+		{In("g.game_id"), pg, "1 = 0", nil},
+		{Not(Eq("g.enabled", true)), pg, "NOT (g.enabled = ?)", []interface{}{true}},
+		{
+			Or(And(Eq("g.enabled", true), Gt("g.rate", 5)), IsNull("g.rate")), pg,
+			"((g.enabled = ? AND g.rate > ?) OR g.rate IS NULL)", []interface{}{true, 5},
+		},
+		{And(), pg, "1 = 1", nil},
+		{Or(), pg, "1 = 0", nil},
+	} {
+		query, args := tt.expr.Expr(tt.dialect)
+		assert.Equal(t, tt.query, query)
+		if len(tt.args) > 0 {
+			assert.Equal(t, tt.args, args)
+		} else {
+			assert.Empty(t, args)
+		}
+	}
+}
@@ -0,0 +1,197 @@
+package tyr
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// EncodeCursor encodes the keyset values of a row into an opaque cursor.
+func EncodeCursor(values ...interface{}) (string, error) {
+	b, err := json.Marshal(values)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// DecodeCursor decodes a cursor made by EncodeCursor, numbers are kept
+// as json.Number so large ids are not rounded.
+func DecodeCursor(cursor string) ([]interface{}, error) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor: %v", err)
+	}
+	var values []interface{}
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+	if err := d.Decode(&values); err != nil {
+		return nil, fmt.Errorf("invalid cursor: %v", err)
+	}
+	return values, nil
+}
+
+// keyset selects the rows after the cursor values in the orders.
+type keyset struct {
+	orders []Order
+	values []interface{}
+}
+
+func (k keyset) Expr(Dialect) (string, []interface{}) {
+	same := true
+	for _, o := range k.orders {
+		same = same && o.Direction == k.orders[0].Direction
+	}
+	if same {
+		columns := make([]string, len(k.orders))
+		params := make([]string, len(k.orders))
+		for i, o := range k.orders {
+			columns[i] = o.Column
+			params[i] = "?"
+		}
+		if len(columns) == 1 {
+			return fmt.Sprintf("%s %s ?", columns[0], k.orders[0].after()), k.values
+		}
+		return fmt.Sprintf("(%s) %s (%s)", strings.Join(columns, ", "), k.orders[0].after(), strings.Join(params, ", ")), k.values
+	}
+
+	// mixed directions can not use a row comparison,
+	// (a > ? OR (a = ? AND b < ?))
+	terms := make([]string, 0, len(k.orders))
+	args := make([]interface{}, 0)
+	for i, o := range k.orders {
+		parts := make([]string, 0, i+1)
+		for j := 0; j < i; j++ {
+			parts = append(parts, fmt.Sprintf("%s = ?", k.orders[j].Column))
+			args = append(args, k.values[j])
+		}
+		parts = append(parts, fmt.Sprintf("%s %s ?", o.Column, o.after()))
+		args = append(args, k.values[i])
+		if len(parts) == 1 {
+			terms = append(terms, parts[0])
+			continue
+		}
+		terms = append(terms, fmt.Sprintf("(%s)", strings.Join(parts, " AND ")))
+	}
+	return fmt.Sprintf("(%s)", strings.Join(terms, " OR ")), args
+}
+
+// after is the operator selecting the rows after a value in the order.
+func (o Order) after() string {
+	if o.Direction == DESC {
+		return "<"
+	}
+	return ">"
+}
+
+// Keyset paginates by the orders instead of LIMIT/OFFSET, selecting the
+// rows after the cursor returned by NextCursor, an empty cursor selects
+// the first page. The orders follow the ORDER BY terms, replacing those
+// of a previous Keyset, and must identify a row uniquely, e.g.
+// Keyset(cursor, Desc("g.release"), Desc("g.game_id")) or
+// Keyset(cursor, "g.release DESC", "g.game_id DESC").
+func (s *Query) Keyset(cursor string, orders ...interface{}) *Query {
+	q := s.clone()
+	q.raw.keyset = nil
+	q.raw.cursor = nil
+	for _, order := range orders {
+		o, err := keysetOrder(order)
+		if err != nil {
+			q.raw.addError(err)
+			return q
+		}
+		q.raw.keyset = append(q.raw.keyset, o)
+	}
+	if len(q.raw.keyset) < 1 {
+		q.raw.addError(fmt.Errorf("keyset requires at least one order"))
+		return q
+	}
+	if len(cursor) < 1 {
+		return q
+	}
+	values, err := DecodeCursor(cursor)
+	if err != nil {
+		q.raw.addError(err)
+		return q
+	}
+	if len(values) != len(q.raw.keyset) {
+		q.raw.addError(fmt.Errorf("cursor has %d values, keyset has %d orders", len(values), len(q.raw.keyset)))
+		return q
+	}
+	q.raw.cursor = values
+	return q
+}
+
+// keysetOrder parses a raw order as a column with an optional direction
+// and nulls order, the cursor condition is rendered from them.
+func keysetOrder(order interface{}) (Order, error) {
+	raw, ok := order.(string)
+	if !ok {
+		return toOrder(order)
+	}
+	words := strings.Fields(raw)
+	if len(words) < 1 {
+		return Order{}, fmt.Errorf("keyset order is empty")
+	}
+	o := Order{Column: words[0]}
+	rest := strings.ToUpper(strings.Join(words[1:], " "))
+	for _, direction := range []string{ASC, DESC} {
+		if rest == direction || strings.HasPrefix(rest, direction+" ") {
+			o.Direction = direction
+			rest = strings.TrimSpace(rest[len(direction):])
+		}
+	}
+	switch rest {
+	case "":
+	case NullsFirst, NullsLast:
+		o.Nulls = rest
+	default:
+		return Order{}, fmt.Errorf("keyset order %q is not a column with ASC or DESC", raw)
+	}
+	return o, nil
+}
+
+// NextCursor encodes the keyset values of the last row of a page, the
+// row is a struct whose sql tags name the keyset columns.
+func (s *Query) NextCursor(last interface{}) (string, error) {
+	if s.raw == nil || len(s.raw.keyset) < 1 {
+		return "", fmt.Errorf("query has no keyset")
+	}
+	v := reflect.Indirect(reflect.ValueOf(last))
+	if v.Kind() != reflect.Struct {
+		return "", fmt.Errorf("last row %T is not a struct", last)
+	}
+	values := make([]interface{}, 0, len(s.raw.keyset))
+	for _, o := range s.raw.keyset {
+		column := o.Column
+		if idx := strings.LastIndex(column, "."); idx >= 0 {
+			column = column[idx+1:]
+		}
+		field, ok := fieldByColumn(s.raw.TagName, v, column)
+		if !ok {
+			return "", fmt.Errorf("column %s has no field in %T", o.Column, last)
+		}
+		value := field.Interface()
+		if valuer, ok := value.(driver.Valuer); ok {
+			var err error
+			if value, err = valuer.Value(); err != nil {
+				return "", err
+			}
+		}
+		values = append(values, value)
+	}
+	return EncodeCursor(values...)
+}
+
+func fieldByColumn(tag string, v reflect.Value, column string) (reflect.Value, bool) {
+	for _, f := range taggedFields(tag, v.Type()) {
+		if f.name == column {
+			return fieldOf(v, f.index)
+		}
+	}
+	return reflect.Value{}, false
+}
@@ -0,0 +1,87 @@
+package tyr
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCursorEncoding(t *testing.T) {
+	cursor, err := EncodeCursor(int64(9007199254740993), "code")
+	assert.NoError(t, err)
+	values, err := DecodeCursor(cursor)
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{json.Number("9007199254740993"), "code"}, values)
+
+	_, err = DecodeCursor("not a cursor")
+	assert.Error(t, err)
+}
+
+func TestRawQuery_Keyset(t *testing.T) {
+	base := Build().From(Game{Enabled: true}, "g").Limit(20).Page(3)
+
+	first := base.Keyset("", Desc("g.rate"), Desc("g.game_id"))
+	query, args := first.ToSQL()
+	assert.Equal(t, "SELECT g.* FROM ref_game g WHERE g.enabled = $1 ORDER BY g.rate DESC, g.game_id DESC LIMIT 20 OFFSET 0", query)
+	assert.Equal(t, 1, len(args))
+
+	last := &Game{ID: 42, Rate: Int64(75)}
+	cursor, err := first.NextCursor(last)
+	assert.NoError(t, err)
+
+	query, args = base.Keyset(cursor, Desc("g.rate"), Desc("g.game_id")).ToSQL()
+	assert.Equal(t, "SELECT g.* FROM ref_game g WHERE (g.enabled = $1) AND (g.rate, g.game_id) < ($2, $3) ORDER BY g.rate DESC, g.game_id DESC LIMIT 20 OFFSET 0", query)
+	assert.Equal(t, []interface{}{true, json.Number("75"), json.Number("42")}, args)
+
+	query, args = base.Keyset(cursor, Asc("g.rate"), Desc("g.game_id")).ToSQL()
+	assert.Contains(t, query, "WHERE (g.enabled = $1) AND (g.rate > $2 OR (g.rate = $3 AND g.game_id < $4)) ORDER BY g.rate ASC, g.game_id DESC")
+	assert.Equal(t, 4, len(args))
+
+	cursor, err = Build().From(Game{}, "g").Keyset("", "g.release").NextCursor(Game{Release: Time(time.Unix(0, 0).UTC())})
+	assert.NoError(t, err)
+	query, _ = Build().SetDialect(MYSQL).From(Game{}, "g").Keyset(cursor, "g.release").ToSQL()
+	assert.Equal(t, "SELECT g.* FROM ref_game g WHERE g.release > ? ORDER BY g.release LIMIT 0, 100", query)
+
+	_, _, err = base.Keyset(cursor, Desc("g.rate"), Desc("g.game_id")).Build()
+	assert.Error(t, err)
+	_, err = base.NextCursor(last)
+	assert.Error(t, err)
+}
+
+func TestRawQuery_KeysetReplaced(t *testing.T) {
+	cursor, _ := EncodeCursor(75, 42)
+	first := Build().From(Game{}, "g").OrderBy("g.game_code").Keyset("", Desc("g.rate"), Desc("g.game_id"))
+	query, args := first.Keyset(cursor, "g.rate DESC", "g.game_id desc").ToSQL()
+	assert.Equal(t, "SELECT g.* FROM ref_game g WHERE (g.rate, g.game_id) < ($1, $2) ORDER BY g.game_code, g.rate DESC, g.game_id DESC LIMIT 100 OFFSET 0", query)
+	assert.Equal(t, []interface{}{json.Number("75"), json.Number("42")}, args)
+
+	query, _ = first.Keyset("", "g.release ASC NULLS LAST").ToSQL()
+	assert.Contains(t, query, "ORDER BY g.game_code, g.release ASC NULLS LAST LIMIT")
+
+	_, _, err := first.Keyset(cursor, "g.rate DESC, g.game_id").Build()
+	assert.Error(t, err)
+	_, _, err = first.Keyset(cursor, "g.rate DOWN", "g.game_id").Build()
+	assert.Error(t, err)
+}
+
+func TestRawQuery_KeysetGrouped(t *testing.T) {
+	cursor, _ := EncodeCursor(42)
+	query, args := Build().From(Game{}, "g").Or(&Game{Code: "x", Title: "y"}, "g").Keyset(cursor, Asc("g.game_id")).ToSQL()
+	assert.Equal(t, "SELECT g.* FROM ref_game g WHERE (g.game_code = $1 OR g.game_title = $2) AND g.game_id > $3 ORDER BY g.game_id ASC LIMIT 100 OFFSET 0", query)
+	assert.Equal(t, []interface{}{"x", "y", json.Number("42")}, args)
+
+	query, _ = Build().From(Game{}, "g").Where("g.game_code = ? OR g.game_title = ?", "x", "y").Keyset(cursor, Asc("g.game_id")).ToSQL()
+	assert.Equal(t, "SELECT g.* FROM ref_game g WHERE (g.game_code = $1 OR g.game_title = $2) AND g.game_id > $3 ORDER BY g.game_id ASC LIMIT 100 OFFSET 0", query)
+
+	query, _ = Build().From(Post{}, "p").Where("p.title = ? OR p.title = ?", "x", "y").Keyset(cursor, Asc("p.id")).ToSQL()
+	assert.Equal(t, "SELECT p.* FROM posts p WHERE (p.title = $1 OR p.title = $2) AND p.deleted_at IS NULL AND p.id > $3 ORDER BY p.id ASC LIMIT 100 OFFSET 0", query)
+}
+
+func TestRawQuery_PageOffset(t *testing.T) {
+	query, _ := Build().From(Game{}, "g").Limit(10).ToSQL()
+	assert.Contains(t, query, "LIMIT 10 OFFSET 0")
+	query, _ = Build().From(Game{}, "g").Page(3).ToSQL()
+	assert.Contains(t, query, "LIMIT 100 OFFSET 200")
+}
@@ -0,0 +1,96 @@
+package tyr
+
+import (
+	"fmt"
+	"regexp"
+)
+
+const (
+	ForUpdate  string = "FOR UPDATE"
+	ForShare   string = "FOR SHARE"
+	NoWait     string = "NOWAIT"
+	SkipLocked string = "SKIP LOCKED"
+)
+
+// locking matches the row locking clauses rendered by the builder.
+var locking = regexp.MustCompile(`\bFOR (UPDATE|SHARE|NO KEY UPDATE|KEY SHARE)\b|\bLOCK IN SHARE MODE\b`)
+
+// lock is the row locking clause of a select.
+type lock struct {
+	strength string
+	wait     string
+}
+
+// ForUpdate locks the selected rows against updates by other
+// transactions, the statement has to run in a transaction, e.g. with
+// TxQueryCtx.
+func (s *Query) ForUpdate() *Query {
+	return s.lock(ForUpdate)
+}
+
+// ForShare locks the selected rows against updates while letting other
+// transactions read and share the lock.
+func (s *Query) ForShare() *Query {
+	return s.lock(ForShare)
+}
+
+// NoWait fails the select instead of waiting for rows locked by another
+// transaction, it replaces SkipLocked.
+func (s *Query) NoWait() *Query {
+	return s.wait(NoWait)
+}
+
+// SkipLocked leaves out the rows locked by another transaction, e.g. to
+// take jobs from a queue, it replaces NoWait.
+func (s *Query) SkipLocked() *Query {
+	return s.wait(SkipLocked)
+}
+
+func (s *Query) lock(strength string) *Query {
+	q := s.clone()
+	if !q.raw.isSelect() {
+		q.raw.addError(fmt.Errorf("%s requires a select built by From", strength))
+		return q
+	}
+	l := lock{strength: strength}
+	if q.raw.lock != nil {
+		l.wait = q.raw.lock.wait
+	}
+	q.raw.lock = &l
+	return q
+}
+
+func (s *Query) wait(wait string) *Query {
+	q := s.clone()
+	if q.raw.lock == nil {
+		q.raw.addError(fmt.Errorf("%s requires ForUpdate or ForShare", wait))
+		return q
+	}
+	l := *q.raw.lock
+	l.wait = wait
+	q.raw.lock = &l
+	return q
+}
+
+// lockQuery writes the row locking clause of the select.
+func (r *SQL) lockQuery(st *statement) {
+	dialect := r.query.Dialect()
+	if !dialect.SupportsRowLocking() {
+		st.errs = append(st.errs, fmt.Errorf("%s is not supported by %s", r.lock.strength, dialect.Name()))
+	}
+	if len(r.compounds) > 0 {
+		st.errs = append(st.errs, fmt.Errorf("%s is not allowed with %s", r.lock.strength, r.compounds[0].operator))
+	}
+	st.WriteString(" ")
+	st.WriteString(r.lock.strength)
+	if len(r.lock.wait) > 0 {
+		st.WriteString(" ")
+		st.WriteString(r.lock.wait)
+	}
+}
+
+// isLocking reports whether the statement locks rows, which only holds
+// for the transaction running it.
+func isLocking(query string) bool {
+	return locking.MatchString(query)
+}
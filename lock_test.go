@@ -0,0 +1,49 @@
+package tyr
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRawQuery_Lock(t *testing.T) {
+	jobs := Build().From(Game{}, "g").Where(Eq("g.enabled", true)).OrderBy(Asc("g.game_id")).Limit(5)
+
+	query, args := jobs.ForUpdate().SkipLocked().ToSQL()
+	assert.Equal(t, "SELECT g.* FROM ref_game g WHERE g.enabled = $1 ORDER BY g.game_id ASC LIMIT 5 OFFSET 0 FOR UPDATE SKIP LOCKED", query)
+	assert.Equal(t, []interface{}{true}, args)
+
+	query, _ = jobs.SetDialect(MYSQL).ForShare().NoWait().ToSQL()
+	assert.Equal(t, "SELECT g.* FROM ref_game g WHERE g.enabled = ? ORDER BY g.game_id ASC LIMIT 0, 5 FOR SHARE NOWAIT", query)
+
+	query, _ = jobs.ForUpdate().NoWait().ForShare().ToSQL()
+	assert.Equal(t, "SELECT g.* FROM ref_game g WHERE g.enabled = $1 ORDER BY g.game_id ASC LIMIT 5 OFFSET 0 FOR SHARE NOWAIT", query)
+
+	query, _ = jobs.ForUpdate().CountQuery().ToSQL()
+	assert.Equal(t, "SELECT COUNT(*) FROM ref_game g WHERE g.enabled = $1", query)
+
+	_, _, err := jobs.SetDialect(SQLITE).ForUpdate().Build()
+	assert.Error(t, err)
+	_, _, err = jobs.SkipLocked().Build()
+	assert.Error(t, err)
+	_, _, err = Build().Delete(Game{ID: 1}).ForUpdate().Build()
+	assert.Error(t, err)
+	_, _, err = jobs.Union(jobs).ForUpdate().Build()
+	assert.Error(t, err)
+}
+
+func TestQueryCtxLocking(t *testing.T) {
+	query, args := Build().From(Game{}, "g").ForUpdate().ToSQL()
+	db := &DB{}
+	err := db.QueryCtx(context.Background(), func(rs *sql.Rows) error { return nil }, query, args...)
+	assert.EqualError(t, err, "event QueryCtx: row locking requires a transaction, use TxQueryCtx")
+	err = db.QueryRowCtx(context.Background(), func(rs *sql.Row) error { return nil }, query, args...)
+	assert.EqualError(t, err, "event QueryRowCtx: row locking requires a transaction, use TxQueryCtx")
+	err = db.TxQueryCtx(context.Background(), nil, func(rs *sql.Rows) error { return nil }, query, args...)
+	assert.Error(t, err)
+
+	assert.True(t, isLocking("SELECT * FROM t LIMIT 1 FOR NO KEY UPDATE"))
+	assert.False(t, isLocking("SELECT * FROM t_for_update"))
+}
@@ -0,0 +1,72 @@
+package tyr
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	ASC  string = "ASC"
+	DESC string = "DESC"
+
+	NullsFirst string = "NULLS FIRST"
+	NullsLast  string = "NULLS LAST"
+)
+
+// Order is a single ORDER BY term, create it with Asc or Desc.
+type Order struct {
+	Column    string
+	Direction string
+	Nulls     string
+}
+
+func Asc(column string) Order {
+	return Order{Column: column, Direction: ASC}
+}
+
+func Desc(column string) Order {
+	return Order{Column: column, Direction: DESC}
+}
+
+func (o Order) NullsFirst() Order {
+	o.Nulls = NullsFirst
+	return o
+}
+
+func (o Order) NullsLast() Order {
+	o.Nulls = NullsLast
+	return o
+}
+
+func toOrder(order interface{}) (Order, error) {
+	switch o := order.(type) {
+	case Order:
+		return o, nil
+	case string:
+		return Order{Column: o}, nil
+	}
+	return Order{}, fmt.Errorf("order by %T is not supported", order)
+}
+
+func (o Order) render(dialect Dialect) string {
+	var buff strings.Builder
+	if len(o.Nulls) > 0 && !dialect.SupportsNullsOrder() {
+		// emulate NULLS FIRST/LAST by sorting on the null check first
+		buff.WriteString(o.Column)
+		if o.Nulls == NullsFirst {
+			buff.WriteString(" IS NOT NULL, ")
+		} else {
+			buff.WriteString(" IS NULL, ")
+		}
+	}
+	buff.WriteString(o.Column)
+	if len(o.Direction) > 0 {
+		buff.WriteString(" ")
+		buff.WriteString(o.Direction)
+	}
+	if len(o.Nulls) > 0 && dialect.SupportsNullsOrder() {
+		buff.WriteString(" ")
+		buff.WriteString(o.Nulls)
+	}
+	return buff.String()
+}
@@ -9,14 +9,16 @@
 package tyr
 
 import (
+	"context"
+	"database/sql/driver"
 	"fmt"
 	"reflect"
 	"sort"
-	"strconv"
 	"strings"
 	"time"
 	"unicode"
 
+	"github.com/lib/pq"
 	"github.com/suryakencana007/mimir"
 )
 
@@ -26,39 +28,104 @@ type Model interface {
 	TableName() string
 }
 
+// SoftDeleter marks a model whose rows are soft deleted, DeletedColumn
+// names the timestamp column set on delete. A field tagged with the
+// softdelete option, e.g. `sql:"deleted_at,softdelete"`, does the same.
+type SoftDeleter interface {
+	DeletedColumn() string
+}
+
+// SchemaNamer places the table of a model in a schema, which qualifies
+// the table in every statement, e.g. billing.invoices.
+type SchemaNamer interface {
+	SchemaName() string
+}
+
 type Query struct {
-	raw    *SQL
-	Query  string
-	Args   []interface{}
-	Rows   int
-	Offset int
+	raw     *SQL
+	dialect Dialect
+	arrays  bool
+	quoted  bool
+	errs    []error
+	ctx     context.Context
+	Query   string
+	Args    []interface{}
+	Rows    int
+	Offset  int
 }
 
 func Build() *Query {
 	return &Query{}
 }
 
+// NewScope returns the scope of a copy of the query bound to the model,
+// the query itself is left untouched. A query holds one statement, so
+// the scope of a query already holding one, e.g. From then Insert, fails
+// to build.
 func (s *Query) NewScope(model interface{}) *SQL {
-	if s.raw == nil {
-		s.raw = &SQL{query: s.clone(), Model: model, TagName: "sql"}
-	} else {
-		s.raw.Model = model
+	q := s.clone()
+	if kind := q.raw.kind(); len(kind) > 0 {
+		q.raw.addError(fmt.Errorf("query already holds a %s of %T, start another one with Build", kind, q.raw.Model))
 	}
-	return s.raw
+	q.raw.Model = model
+	return q.raw
 }
 
 func (s *Query) SetTag(tag string) *Query {
-	s.clone().raw.TagName = tag
-	return s
+	q := s.clone()
+	q.raw.TagName = tag
+	return q
+}
+
+// SetDialect selects the backend the statement is rendered for,
+// the driver is one of POSTGRES, MYSQL or SQLITE.
+func (s *Query) SetDialect(driver string) *Query {
+	q := s.clone()
+	d, err := GetDialect(driver)
+	if err != nil {
+		q.raw.addError(err)
+		return q
+	}
+	q.dialect = d
+	return q
+}
+
+// AnyArray binds slice args as a single Postgres array, rewriting
+// "IN (?)" into "= ANY(?)", instead of expanding them.
+func (s *Query) AnyArray() *Query {
+	q := s.clone()
+	q.arrays = true
+	return q
+}
+
+// QuoteIdentifiers quotes the tables and the tagged columns written by
+// the builder with the dialect, e.g. "user" or `order`, so reserved and
+// mixed-case names can be used. Aliases and hand written columns are
+// left as is. The statement is quoted when built, whenever it is called.
+func (s *Query) QuoteIdentifiers() *Query {
+	q := s.clone()
+	q.quoted = true
+	return q
 }
+
+// Dialect returns the dialect of the query, Postgres when none is set.
+func (s *Query) Dialect() Dialect {
+	if s.dialect == nil {
+		return postgresDialect{}
+	}
+	return s.dialect
+}
+
 func (s *Query) Limit(limit int) *Query {
-	s.Rows = limit
-	return s
+	q := s.clone()
+	q.Rows = limit
+	return q
 }
 
 func (s *Query) Page(page int) *Query {
-	s.Offset = page
-	return s
+	q := s.clone()
+	q.Offset = page
+	return q
 }
 
 func (s *Query) Or(model interface{}, alias string) *Query {
@@ -69,9 +136,28 @@ func (s *Query) And(model interface{}, alias string) *Query {
 	return s.clone().raw.operator(model, alias, "AND").query
 }
 
+// Join renders an inner join, every on condition is either a raw string,
+// a column pair built with On or an Expr, joined with AND.
+//
 // join ref_user u on u.id = g.user_id
 func (s *Query) Join(model interface{}, alias string, on ...interface{}) *Query {
-	return s.clone().raw.join(model, alias, on...).query
+	return s.clone().raw.join("JOIN", model, alias, on...).query
+}
+
+func (s *Query) LeftJoin(model interface{}, alias string, on ...interface{}) *Query {
+	return s.clone().raw.join("LEFT JOIN", model, alias, on...).query
+}
+
+func (s *Query) RightJoin(model interface{}, alias string, on ...interface{}) *Query {
+	return s.clone().raw.join("RIGHT JOIN", model, alias, on...).query
+}
+
+func (s *Query) FullJoin(model interface{}, alias string, on ...interface{}) *Query {
+	return s.clone().raw.join("FULL JOIN", model, alias, on...).query
+}
+
+func (s *Query) CrossJoin(model interface{}, alias string) *Query {
+	return s.clone().raw.join("CROSS JOIN", model, alias).query
 }
 
 func (s *Query) From(model interface{}, alias string) *Query {
@@ -90,29 +176,244 @@ func (s *Query) Updates(model interface{}) *Query {
 	return s.NewScope(model).updates().query
 }
 
+// Fields limits Updates to the columns, which are written even when
+// their value is empty, e.g. Updates(game).Fields("enabled", "rate")
+// sets enabled to false and rate to NULL on a zero Game.
+func (s *Query) Fields(columns ...string) *Query {
+	q := s.clone()
+	r := q.raw
+	if r.update == nil {
+		r.addError(fmt.Errorf("fields requires Updates"))
+		return q
+	}
+	known := modelColumns(r.TagName, r.Model)
+	for _, column := range columns {
+		if !contains(known, column) || column == r.ID {
+			r.addError(fmt.Errorf("field %s can not be updated on %T", column, r.Model))
+			return q
+		}
+	}
+	u := *r.update
+	u.fields = columns
+	r.update = &u
+	return q
+}
+
+// Upsert inserts the model or updates the row conflicting on the
+// conflict columns, the model id column when none are given.
+func (s *Query) Upsert(model interface{}, conflictColumns ...string) *Query {
+	return s.NewScope(model).insert().upsert(conflictColumns).query
+}
+
+// Upserts is the bulk variant of Upsert.
+func (s *Query) Upserts(models interface{}, conflictColumns ...string) *Query {
+	return s.NewScope(models).inserts().upsert(conflictColumns).query
+}
+
+// DoUpdate limits the columns updated on conflict, every inserted
+// column except the conflict columns and the created audit columns by
+// default.
+func (s *Query) DoUpdate(columns ...string) *Query {
+	q := s.clone()
+	if c := q.raw.conflict; c != nil {
+		c.update = columns
+		c.nothing = false
+	}
+	return q
+}
+
+// DoNothing keeps the existing row on conflict.
+func (s *Query) DoNothing() *Query {
+	q := s.clone()
+	if c := q.raw.conflict; c != nil {
+		c.nothing = true
+	}
+	return q
+}
+
+// Delete deletes the rows matching the non-empty fields of the model,
+// soft deleted models are updated with the deletion time instead.
+func (s *Query) Delete(model interface{}) *Query {
+	return s.NewScope(model).delete().query
+}
+
+// Unscoped disables soft delete for the following From and Delete,
+// rows are hard deleted and soft deleted rows are selected.
+func (s *Query) Unscoped() *Query {
+	q := s.clone()
+	q.raw.unscoped = true
+	return q
+}
+
 func (s *Query) Where(query interface{}, args ...interface{}) *Query {
 	return s.clone().raw.Where(query, args...).query
 }
 
-func (s *Query) ToSQL() (string, []interface{}) {
-	s.raw.Exec()
-	if strings.Contains(strings.ToLower(s.Query), "update") || strings.Contains(strings.ToLower(s.Query), "insert") {
-		var buff strings.Builder
-		buff.Reset()
-		buff.WriteString(s.Query)
-		buff.WriteString(" RETURNING ")
-		buff.WriteString(s.raw.ID)
-		s.Query = buff.String()
-		buff.Reset()
+// Returning sets the columns of the RETURNING clause rendered for
+// INSERT and UPDATE statements, the model id column by default.
+func (s *Query) Returning(columns ...string) *Query {
+	q := s.clone()
+	q.raw.returning = columns
+	return q
+}
+
+// Select sets the projection of the SELECT statement, without columns
+// it is derived from the sql tags of the From and Join models.
+func (s *Query) Select(columns ...string) *Query {
+	q := s.clone()
+	q.raw.columns = columns
+	q.raw.autoColumns = len(columns) < 1
+	return q
+}
+
+// OrderBy appends ORDER BY terms, each one either an Order built
+// with Asc or Desc or a raw string such as "g.game_id DESC".
+func (s *Query) OrderBy(orders ...interface{}) *Query {
+	return s.clone().raw.order(orders...).query
+}
+
+func (s *Query) GroupBy(columns ...string) *Query {
+	q := s.clone()
+	q.raw.groupBy = append(q.raw.groupBy, columns...)
+	return q
+}
+
+func (s *Query) Having(query interface{}, args ...interface{}) *Query {
+	return s.clone().raw.Having(query, args...).query
+}
+
+// AddError returns a copy of the query failing to build with err.
+func (s *Query) AddError(err error) *Query {
+	q := s.clone()
+	q.raw.addError(err)
+	return q
+}
+
+// Err returns the errors raised while building the statement, nil
+// when the statement is valid.
+func (s *Query) Err() error {
+	if _, _, err := s.Build(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Build renders the statement like ToSQL and reports the errors raised
+// while building it. Rendering leaves the query untouched, so it can
+// be built any number of times and from several goroutines.
+func (s *Query) Build() (string, []interface{}, error) {
+	if s.raw == nil {
+		return (&SQL{query: s, TagName: "sql"}).Exec()
 	}
-	return s.Query, s.Args
+	return s.raw.Exec()
 }
 
+// ToSQL renders the statement, it returns an empty query when building
+// failed, use Build to get the error.
+func (s *Query) ToSQL() (string, []interface{}) {
+	query, args, _ := s.Build()
+	return query, args
+}
+
+// clone copies the query and its scope, so a builder method never
+// changes a query shared with another branch.
 func (s *Query) clone() *Query {
+	q := *s
+	q.Args = append([]interface{}(nil), s.Args...)
+	q.errs = append([]error(nil), s.errs...)
 	if s.raw == nil {
-		s.raw = &SQL{query: s, TagName: "sql"}
+		q.raw = &SQL{query: &q, TagName: "sql"}
+		return &q
+	}
+	r := *s.raw
+	r.query = &q
+	r.returning = append([]string(nil), r.returning...)
+	r.whereConditions = append([]map[string]interface{}(nil), r.whereConditions...)
+	r.joins = append([]joinClause(nil), r.joins...)
+	r.sources = append([]source(nil), r.sources...)
+	r.columns = append([]string(nil), r.columns...)
+	r.groupBy = append([]string(nil), r.groupBy...)
+	r.havingConditions = append([]map[string]interface{}(nil), r.havingConditions...)
+	r.orderBy = append([]Order(nil), r.orderBy...)
+	r.keyset = append([]Order(nil), r.keyset...)
+	r.cursor = append([]interface{}(nil), r.cursor...)
+	r.ctes = append([]cte(nil), r.ctes...)
+	r.compounds = append([]compound(nil), r.compounds...)
+	if r.conflict != nil {
+		c := *r.conflict
+		r.conflict = &c
+	}
+	q.raw = &r
+	return &q
+}
+
+// BuildError lists the errors raised while building a statement.
+type BuildError []error
+
+func (e BuildError) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
 	}
-	return s
+	return strings.Join(messages, "; ")
+}
+
+// statement collects the rendered query, its args and the errors
+// raised while rendering.
+type statement struct {
+	strings.Builder
+	args []interface{}
+	errs []error
+}
+
+type joinClause struct {
+	kind  string
+	model interface{}
+	alias string
+	on    []Expr
+}
+
+// fieldCondition compares the tagged columns of a model, they are quoted
+// when the statement is built as QuoteIdentifiers may follow the model.
+type fieldCondition struct {
+	alias    string
+	columns  []string
+	format   string
+	operator string
+}
+
+type source struct {
+	model interface{}
+	alias string
+}
+
+// insertion is the INSERT of Insert and Inserts, rendered with the
+// models when the statement is built.
+type insertion struct {
+	rows  []interface{}
+	audit []auditColumn
+	skip  []string
+}
+
+// updateSet is the SET clause of Updates, rendered with the model when
+// the statement is built.
+type updateSet struct {
+	audit  []auditColumn
+	skip   []string
+	fields []string
+}
+
+// deletion is the statement of Delete, an UPDATE of the soft delete
+// column when set, rendered when the statement is built.
+type deletion struct {
+	soft string
+}
+
+type conflict struct {
+	columns []string
+	update  []string
+	nothing bool
+	created []string
 }
 
 type SQL struct {
@@ -120,254 +421,943 @@ type SQL struct {
 	ID              string
 	TagName         string
 	query           *Query
+	returning       []string
+	unscoped        bool
+	conflict        *conflict
+	insertion       *insertion
+	update          *updateSet
+	deletion        *deletion
 	whereConditions []map[string]interface{}
+	// scope is the soft delete condition ANDed onto the conditions
+	scope fieldCondition
+
+	from        string
+	joins       []joinClause
+	sources     []source
+	columns     []string
+	autoColumns bool
+
+	groupBy          []string
+	havingConditions []map[string]interface{}
+	orderBy          []Order
+	keyset           []Order
+	cursor           []interface{}
+	derived          *derived
+
+	ctes      []cte
+	recursive bool
+	compounds []compound
+	lock      *lock
 }
 
-func (r *SQL) Where(query interface{}, values ...interface{}) *SQL {
-	if len(r.whereConditions) > 0 {
-		query = fmt.Sprintf(" AND %s", query)
+// returningColumns reports the RETURNING columns of an INSERT or UPDATE,
+// nil when the dialect has no RETURNING and falls back to LastInsertId.
+func (r *SQL) returningColumns() []string {
+	if !r.query.Dialect().SupportsReturning() {
+		return nil
+	}
+	if r.insertion == nil && r.update == nil && (r.deletion == nil || len(r.deletion.soft) < 1) {
+		return nil
+	}
+	if len(r.returning) > 0 {
+		return r.idents(r.returning)
+	}
+	if len(r.ID) > 0 {
+		return []string{r.ident(r.ID)}
 	}
-	r.whereConditions = append(r.whereConditions, map[string]interface{}{"query": query, "args": values})
+	return nil
+}
+
+func (r *SQL) Where(query interface{}, values ...interface{}) *SQL {
+	r.whereConditions = appendCondition(r.whereConditions, query, values)
 	return r
 }
 
-func (r *SQL) Exec() {
-	var buff strings.Builder
-	buff.WriteString(r.query.Query)
+// appendCondition joins the condition with AND, an Expr is kept as is
+// and rendered along with its args when the statement is built. The
+// conditions joined by OR so far are grouped in parentheses first, and
+// so is a raw condition holding an OR, so the AND applies to all of them.
+func appendCondition(conditions []map[string]interface{}, query interface{}, values []interface{}) []map[string]interface{} {
+	conditions = groupDisjunction(conditions)
+	condition := map[string]interface{}{"query": query, "args": values}
+	switch query.(type) {
+	case Expr, fieldCondition:
+		if len(conditions) > 0 {
+			condition["prefix"] = " AND "
+		}
+		return append(conditions, condition)
+	}
+	if text, ok := query.(string); ok && isDisjunction(text) {
+		if len(conditions) < 1 {
+			condition["or"] = true
+			return append(conditions, condition)
+		}
+		query = fmt.Sprintf("(%s)", text)
+	}
+	if len(conditions) > 0 {
+		condition["query"] = fmt.Sprintf(" AND %s", query)
+	}
+	return append(conditions, condition)
+}
+
+// groupDisjunction wraps the conditions in a single group when any of
+// them is joined by OR.
+func groupDisjunction(conditions []map[string]interface{}) []map[string]interface{} {
+	for _, w := range conditions {
+		if or, _ := w["or"].(bool); or {
+			return []map[string]interface{}{{"group": conditions}}
+		}
+	}
+	return conditions
+}
+
+func isDisjunction(query string) bool {
+	return strings.Contains(strings.ToUpper(query), " OR ")
+}
+
+// Exec renders the statement of the scope without changing it.
+func (r *SQL) Exec() (string, []interface{}, error) {
+	st := &statement{}
+	r.render(st, false)
+	if len(st.errs) > 0 {
+		return "", nil, BuildError(st.errs)
+	}
+	return st.String(), st.args, nil
+}
+
+// render writes the statement into st, numbering its placeholders after
+// the args already bound. A nested select only keeps an explicit LIMIT.
+func (r *SQL) render(st *statement, nested bool) {
+	dialect := r.query.Dialect()
+	st.errs = append(st.errs, r.query.errs...)
+	if len(r.ctes) > 0 {
+		r.withQuery(st)
+	}
+	for _, arg := range r.query.Args {
+		st.args = append(st.args, r.bindArg(arg))
+	}
+	if r.derived != nil {
+		st.WriteString(r.derived.head)
+	}
+	var inserted []string
+	switch {
+	case r.isSelect():
+		r.selectQuery(st)
+	case r.insertion != nil:
+		inserted = r.insertQuery(st)
+	case r.update != nil:
+		r.updateQuery(st)
+	case r.deletion != nil:
+		r.deleteQuery(st)
+	default:
+		st.WriteString(r.query.Query)
+	}
+	if r.conflict != nil {
+		st.WriteString(" ")
+		st.WriteString(r.onConflict(st, inserted))
+	}
+	r.whereQuery(st)
+	if len(r.groupBy) > 0 {
+		st.WriteString(" GROUP BY ")
+		st.WriteString(strings.Join(r.groupBy, ", "))
+	}
+	if len(r.havingConditions) > 0 {
+		st.WriteString(" HAVING ")
+		r.conditions(st, r.havingConditions)
+	}
+	for _, c := range r.compounds {
+		st.WriteString(" ")
+		st.WriteString(c.operator)
+		st.WriteString(" ")
+		r.operand(st, c)
+	}
+	if r.derived != nil {
+		st.WriteString(r.derived.tail)
+		return
+	}
+	if orders := append(r.orderBy[:len(r.orderBy):len(r.orderBy)], r.keyset...); len(orders) > 0 {
+		st.WriteString(" ORDER BY ")
+		for i, o := range orders {
+			if i > 0 {
+				st.WriteString(", ")
+			}
+			st.WriteString(o.render(dialect))
+		}
+	}
+	if r.isSelect() && (!nested || r.query.Rows > 0 || r.query.Offset > 0) {
+		limit := r.query.Rows
+		if limit < 1 {
+			limit = 100
+		}
+		offset := 0
+		if r.query.Offset > 1 && len(r.keyset) < 1 {
+			offset = limit * (r.query.Offset - 1)
+		}
+		st.WriteString(" ")
+		st.WriteString(dialect.LimitOffset(limit, offset))
+	}
+	if r.lock != nil {
+		r.lockQuery(st)
+	}
+	if columns := r.returningColumns(); len(columns) > 0 {
+		st.WriteString(" RETURNING ")
+		st.WriteString(strings.Join(columns, ", "))
+	}
+}
+
+// whereQuery writes the WHERE clause. The conditions are grouped in
+// parentheses before the soft delete scope and the keyset cursor are
+// ANDed onto them, so an OR among them can not select the deleted rows
+// or the rows of the previous pages.
+func (r *SQL) whereQuery(st *statement) {
+	guards := make([]map[string]interface{}, 0)
+	if len(r.scope.columns) > 0 {
+		guards = appendCondition(guards, r.scope, nil)
+	}
+	if len(r.cursor) > 0 && r.derived == nil {
+		guards = appendCondition(guards, keyset{orders: r.keyset, values: r.cursor}, nil)
+	}
+	if len(r.whereConditions) < 1 && len(guards) < 1 {
+		return
+	}
+	st.WriteString(" WHERE ")
+	if len(guards) < 1 {
+		r.conditions(st, r.whereConditions)
+		return
+	}
 	if len(r.whereConditions) > 0 {
-		buff.WriteString(" WHERE ")
-		for _, w := range r.whereConditions {
-			lenArgs := len(r.query.Args)
-			query := w["query"].(string)
-			lenQuestion := strings.Count(query, "?")
-			for i := 1; i <= lenQuestion; i++ {
-				query = strings.Replace(query, "?", fmt.Sprintf("$%d", lenArgs+i), 1)
+		st.WriteString("(")
+		r.conditions(st, r.whereConditions)
+		st.WriteString(") AND ")
+	}
+	r.conditions(st, guards)
+}
+
+func (r *SQL) addError(err error) {
+	r.query.errs = append(r.query.errs, err)
+}
+
+// kind names the statement held by the scope, empty when none.
+func (r *SQL) kind() string {
+	switch {
+	case r.isSelect():
+		return "select"
+	case r.insertion != nil:
+		return "insert"
+	case r.update != nil:
+		return "update"
+	case r.deletion != nil:
+		return "delete"
+	}
+	return ""
+}
+
+func (r *SQL) isSelect() bool {
+	return len(r.from) > 0
+}
+
+// selectQuery writes the SELECT projection followed by the FROM and
+// JOIN clauses into the statement.
+func (r *SQL) selectQuery(st *statement) {
+	buff := &st.Builder
+	buff.WriteString("SELECT ")
+	switch {
+	case len(r.columns) > 0:
+		buff.WriteString(strings.Join(r.columns, ", "))
+	case r.autoColumns:
+		buff.WriteString(strings.Join(r.projection(), ", "))
+	default:
+		for i, src := range r.sources {
+			if i > 0 {
+				buff.WriteString(", ")
 			}
-			buff.WriteString(query)
-			args := w["args"].([]interface{})
-			r.query.Args = append(r.query.Args, args...)
+			buff.WriteString(fmt.Sprintf("%s.*", src.alias))
 		}
 	}
-	if strings.Contains(r.query.Query, "SELECT") {
-		buff.WriteString(" LIMIT ")
-		if r.query.Rows > 0 {
-			buff.WriteString(strconv.Itoa(r.query.Rows))
+	buff.WriteString(" FROM ")
+	buff.WriteString(r.sourceTable(r.sources[0].model))
+	buff.WriteString(" ")
+	buff.WriteString(r.sources[0].alias)
+	for _, join := range r.joins {
+		r.joinQuery(st, join)
+	}
+}
+
+func (r *SQL) joinQuery(st *statement, join joinClause) {
+	dialect := r.query.Dialect()
+	if join.kind == "FULL JOIN" && dialect.Name() == MYSQL {
+		st.errs = append(st.errs, fmt.Errorf("FULL JOIN is not supported by %s", dialect.Name()))
+	}
+	st.WriteString(fmt.Sprintf(" %s %s %s", join.kind, r.sourceTable(join.model), join.alias))
+	for i, on := range join.on {
+		if i > 0 {
+			st.WriteString(" AND ")
 		} else {
-			buff.WriteString("100")
+			st.WriteString(" ON ")
+		}
+		query, args := on.Expr(dialect)
+		r.bind(st, query, args)
+	}
+}
+
+// projection lists the tagged columns of every source, columns of the
+// joined models are aliased as alias__column to keep them unique.
+func (r *SQL) projection() []string {
+	columns := make([]string, 0)
+	for i, src := range r.sources {
+		for _, column := range modelColumns(r.TagName, src.model) {
+			if i == 0 {
+				columns = append(columns, fmt.Sprintf("%s.%s", src.alias, r.ident(column)))
+				continue
+			}
+			columns = append(columns, fmt.Sprintf("%s.%s AS %s", src.alias, r.ident(column), r.ident(src.alias+"__"+column)))
+		}
+	}
+	return columns
+}
+
+// conditions writes the conditions into the statement, rewriting every ?
+// into the dialect placeholder numbered after the args already bound.
+func (r *SQL) conditions(st *statement, conditions []map[string]interface{}) {
+	for _, w := range conditions {
+		if group, ok := w["group"].([]map[string]interface{}); ok {
+			st.WriteString("(")
+			r.conditions(st, group)
+			st.WriteString(")")
+			continue
+		}
+		query, args := r.condition(st, w)
+		r.bind(st, query, args)
+	}
+}
+
+// bind writes the query with its ? placeholders numbered and binds the
+// args into the statement, a slice arg of IN (?) is expanded
+// into a placeholder list, or bound as a Postgres array with = ANY when
+// the query has AnyArray set. A slice anywhere else is a build error.
+func (r *SQL) bind(st *statement, query string, args []interface{}) {
+	dialect := r.query.Dialect()
+	array := r.query.arrays && dialect.Name() == POSTGRES
+	buff := &st.Builder
+	bound := make([]interface{}, 0, len(args))
+	next := len(st.args)
+	placeholder := func() string {
+		next++
+		return dialect.Placeholder(next)
+	}
+	n := 0
+	for {
+		idx := strings.Index(query, "?")
+		if idx < 0 {
+			break
+		}
+		segment := query[:idx]
+		query = query[idx+1:]
+		if n >= len(args) {
+			n++
+			buff.WriteString(segment)
+			buff.WriteString(placeholder())
+			continue
 		}
-		buff.WriteString(" OFFSET ")
-		buff.WriteString(strconv.Itoa(r.query.Rows * (r.query.Offset - 1)))
+		arg := args[n]
+		n++
+		if sub, ok := arg.(*Query); ok {
+			// the subquery binds its own args, flush ours first
+			buff.WriteString(segment)
+			st.args = append(st.args, bound...)
+			bound = bound[:0]
+			if enclosed(segment, query) {
+				r.subselect(st, sub)
+			} else {
+				r.subquery(st, sub)
+			}
+			next = len(st.args)
+			continue
+		}
+		v, ok := sliceArg(arg)
+		switch {
+		case !ok:
+			buff.WriteString(segment)
+			buff.WriteString(placeholder())
+			bound = append(bound, arg)
+		case array:
+			buff.WriteString(anyArray(segment))
+			buff.WriteString(placeholder())
+			bound = append(bound, pq.Array(arg))
+		case !inList(segment):
+			st.errs = append(st.errs, fmt.Errorf("slice bound to placeholder %d of %q outside IN (?), use In or AnyArray", n, segment+"?"))
+			buff.WriteString(segment)
+			buff.WriteString(placeholder())
+			bound = append(bound, nil)
+		case v.Len() < 1:
+			st.errs = append(st.errs, fmt.Errorf("empty slice bound to placeholder %d of %q", n, segment+"?"))
+			buff.WriteString(segment)
+			buff.WriteString(placeholder())
+			bound = append(bound, nil)
+		default:
+			buff.WriteString(segment)
+			for i := 0; i < v.Len(); i++ {
+				if i > 0 {
+					buff.WriteString(", ")
+				}
+				buff.WriteString(placeholder())
+				bound = append(bound, v.Index(i).Interface())
+			}
+		}
+	}
+	buff.WriteString(query)
+	if n < len(args) {
+		bound = append(bound, args[n:]...)
+	}
+	st.args = append(st.args, bound...)
+}
+
+// subquery writes the select of the query in parentheses, rendered for
+// the dialect of the enclosing statement.
+func (r *SQL) subquery(st *statement, query *Query) {
+	st.WriteString("(")
+	r.subselect(st, query)
+	st.WriteString(")")
+}
+
+// subselect writes the select of the query as is, for a placeholder
+// already enclosed in parentheses, e.g. IN (?).
+func (r *SQL) subselect(st *statement, query *Query) {
+	sub := query.clone()
+	sub.dialect = r.query.Dialect()
+	sub.arrays = r.query.arrays
+	if !sub.raw.isSelect() {
+		st.errs = append(st.errs, fmt.Errorf("subquery requires a select built by From"))
+	}
+	sub.raw.render(st, true)
+}
+
+// sliceArg reports whether the arg is a slice to expand, byte slices and
+// driver.Valuer implementations are bound as a single value.
+func sliceArg(arg interface{}) (reflect.Value, bool) {
+	if _, ok := arg.(driver.Valuer); ok || arg == nil {
+		return reflect.Value{}, false
+	}
+	v := reflect.ValueOf(arg)
+	if v.Kind() != reflect.Slice || v.Type().Elem().Kind() == reflect.Uint8 {
+		return reflect.Value{}, false
+	}
+	return v, true
+}
+
+// anyArray rewrites a segment ending with IN ( into = ANY(.
+func anyArray(segment string) string {
+	if !inList(segment) {
+		return segment
+	}
+	trimmed := strings.TrimRight(segment, " ")
+	trimmed = strings.TrimRight(trimmed[:len(trimmed)-1], " ")
+	return trimmed[:len(trimmed)-2] + "= ANY("
+}
+
+// enclosed reports whether the placeholder between the segment and the
+// rest of the query is already in parentheses.
+func enclosed(segment, rest string) bool {
+	return strings.HasSuffix(strings.TrimRight(segment, " "), "(") && strings.HasPrefix(strings.TrimLeft(rest, " "), ")")
+}
+
+// inList reports whether the segment ends with IN (, where a slice is
+// expanded into a placeholder list.
+func inList(segment string) bool {
+	trimmed := strings.TrimRight(segment, " ")
+	if !strings.HasSuffix(trimmed, "(") {
+		return false
 	}
+	trimmed = strings.TrimRight(trimmed[:len(trimmed)-1], " ")
+	return strings.HasSuffix(strings.ToUpper(trimmed), " IN")
+}
 
-	r.query.Query = buff.String()
-	buff.Reset()
+func (r *SQL) condition(st *statement, w map[string]interface{}) (string, []interface{}) {
+	prefix, _ := w["prefix"].(string)
+	switch c := w["query"].(type) {
+	case Expr:
+		query, args := c.Expr(r.query.Dialect())
+		return prefix + query, args
+	case fieldCondition:
+		return prefix + r.fieldCondition(c), w["args"].([]interface{})
+	}
+	query, ok := w["query"].(string)
+	if !ok {
+		st.errs = append(st.errs, fmt.Errorf("condition %T is not supported", w["query"]))
+	}
+	return query, w["args"].([]interface{})
 }
 
-func (r *SQL) join(model interface{}, alias string, on ...interface{}) *SQL {
-	if len(on) < 1 && !strings.Contains(r.query.Query, "SELECT") {
-		panic("select syntax or join field not found")
+func (r *SQL) Having(query interface{}, values ...interface{}) *SQL {
+	r.havingConditions = appendCondition(r.havingConditions, query, values)
+	return r
+}
+
+func (r *SQL) order(orders ...interface{}) *SQL {
+	for _, order := range orders {
+		o, err := toOrder(order)
+		if err != nil {
+			r.addError(err)
+			continue
+		}
+		r.orderBy = append(r.orderBy, o)
 	}
+	return r
+}
 
-	// find "from" word on query
-	var idx int
-	if idx = strings.Index(r.query.Query, "FROM"); idx < 1 {
-		panic("FROM syntax not found")
+func (r *SQL) join(kind string, model interface{}, alias string, on ...interface{}) *SQL {
+	if len(r.from) < 1 {
+		r.addError(fmt.Errorf("FROM syntax not found"))
+		return r
+	}
+	table, ok := r.tableName(model)
+	if !ok {
+		return r
+	}
+	for _, src := range r.sources {
+		if src.alias == alias {
+			r.addError(fmt.Errorf("alias %q is already used", alias))
+			return r
+		}
 	}
 
-	var buff strings.Builder
-	buff.Reset()
-	buff.WriteString(strings.TrimRight(r.query.Query[:idx], " ")) // select syntax
-	buff.WriteString(", ")
-	buff.WriteString(fmt.Sprintf("%s.* ", alias))
-	buff.WriteString(r.query.Query[idx:]) //from syntax
-	buff.WriteString(" JOIN ")
-	buff.WriteString(fmt.Sprintf("%s %s", model.(Model).TableName(), alias))
-	buff.WriteString(" ON ")
+	join := joinClause{kind: kind, model: model, alias: alias}
 	for _, arg := range on {
-		buff.WriteString(arg.(string)) // don't forget to assign alias
+		switch cond := arg.(type) {
+		case Expr:
+			join.on = append(join.on, cond)
+		case string:
+			join.on = append(join.on, Raw(cond)) // don't forget to assign alias
+		default:
+			r.addError(fmt.Errorf("join condition %T is not supported", arg))
+			return r
+		}
+	}
+	if len(join.on) < 1 && kind != "CROSS JOIN" {
+		r.addError(fmt.Errorf("%s %s requires an ON condition", kind, table))
+		return r
 	}
-	r.query.Query = buff.String()
-	buff.Reset()
+	r.joins = append(r.joins, join)
+	r.sources = append(r.sources, source{model: model, alias: alias})
 	return r
 }
 
+// tableName returns the table of the model qualified by its schema,
+// recording an error when the model does not implement Model.
+func (r *SQL) tableName(model interface{}) (string, bool) {
+	if isNilModel(model) {
+		r.addError(fmt.Errorf("model %T is nil", model))
+		return "", false
+	}
+	m, ok := model.(Model)
+	if !ok {
+		r.addError(fmt.Errorf("model %T does not implement Model", model))
+		return "", false
+	}
+	return r.qualifiedName(m), true
+}
+
+// isNilModel reports whether the model is nil or a nil pointer, whose
+// value methods such as TableName would panic.
+func isNilModel(model interface{}) bool {
+	if model == nil {
+		return true
+	}
+	v := reflect.ValueOf(model)
+	return v.Kind() == reflect.Ptr && v.IsNil()
+}
+
+// sourceTable is the qualified table of a model selected by From or
+// Join, checked to implement Model when it was added.
+func (r *SQL) sourceTable(model interface{}) string {
+	return r.qualifiedName(model.(Model))
+}
+
+// fieldCondition renders the condition with the columns quoted when the
+// query quotes identifiers.
+func (r *SQL) fieldCondition(c fieldCondition) string {
+	terms := make([]string, len(c.columns))
+	for i, column := range c.columns {
+		column = r.ident(column)
+		if len(c.alias) > 0 {
+			column = c.alias + "." + column
+		}
+		terms[i] = fmt.Sprintf(c.format, column)
+	}
+	return strings.Join(terms, c.operator)
+}
+
+func (r *SQL) qualifiedName(m Model) string {
+	table := r.ident(m.TableName())
+	if s, ok := m.(SchemaNamer); ok && len(s.SchemaName()) > 0 {
+		return r.ident(s.SchemaName()) + "." + table
+	}
+	return table
+}
+
+// ident quotes the identifier when the query quotes identifiers.
+func (r *SQL) ident(name string) string {
+	if !r.query.quoted || name == "*" {
+		return name
+	}
+	return r.query.Dialect().Quote(name)
+}
+
+func (r *SQL) idents(names []string) []string {
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = r.ident(name)
+	}
+	return quoted
+}
+
 func (r *SQL) modelAlias(tableName string) string {
 	return strings.Replace(fmt.Sprintf("%s ?", tableName), "?", mimir.ToCamel(tableName), -1)
 }
 
 func (r *SQL) operator(model interface{}, alias, operator string) *SQL {
-	if !strings.Contains(r.query.Query, "SELECT") {
-		panic("select syntax or join field not found")
+	if len(r.from) < 1 {
+		r.addError(fmt.Errorf("select syntax or join field not found"))
+		return r
 	}
 
 	columns, err := r.fieldsToArgs(
 		model,
 		func(key string, n int, opts tagOptions) string {
-			return fmt.Sprintf("%s.%s = ?", alias, key)
+			return key
 		},
 	)
 	if err != nil {
-		panic(err.Error())
+		r.addError(err)
+		return r
 	}
 
 	if len(columns) > 0 {
-		var buff strings.Builder
-		buff.Reset()
-		args := r.query.Args
+		if operator != "OR" {
+			r.whereConditions = groupDisjunction(r.whereConditions)
+		}
+		join := fmt.Sprintf(" %s ", operator)
+		condition := map[string]interface{}{
+			"query": fieldCondition{alias: alias, columns: columns, format: "%s = ?", operator: join},
+			"args":  r.query.Args,
+			"or":    operator == "OR",
+		}
 		if len(r.whereConditions) > 0 {
-			buff.WriteString(fmt.Sprintf(" %s ", operator))
+			condition["prefix"] = join
 		}
-		buff.WriteString(strings.Join(columns, fmt.Sprintf(" %s ", operator)))
-		r.whereConditions = append(r.whereConditions, map[string]interface{}{"query": buff.String(), "args": args})
-		buff.Reset()
+		r.whereConditions = append(r.whereConditions, condition)
 		r.query.Args = nil
 	}
 	return r
 }
 
 func (r *SQL) fromQuery(alias string) *SQL {
+	table, ok := r.tableName(r.Model)
+	if !ok {
+		return r
+	}
+	deleted := r.softDeleteColumn(r.Model)
 	columns, err := r.fieldsToArgs(
 		r.Model,
 		func(key string, n int, opts tagOptions) string {
-			return fmt.Sprintf("%s.%s = ?", alias, key)
+			if key == deleted {
+				return ""
+			}
+			return key
 		},
 	)
 	if err != nil {
-		panic(err.Error())
+		r.addError(err)
+		return r
 	}
-	var buff strings.Builder
-	buff.Reset()
-	buff.WriteString("SELECT ")
-	buff.WriteString(fmt.Sprintf("%s.* ", alias))
-	buff.WriteString("FROM ")
-	buff.WriteString(fmt.Sprintf("%s %s", r.Model.(Model).TableName(), alias))
-	r.query.Query = buff.String()
-	buff.Reset()
+	r.scope = fieldCondition{}
+	if len(deleted) > 0 {
+		r.scope = fieldCondition{alias: alias, columns: []string{deleted}, format: "%s IS NULL"}
+	}
+	r.from = fmt.Sprintf("%s %s", table, alias)
+	r.sources = append(r.sources[:0], source{model: r.Model, alias: alias})
 	if len(columns) > 0 {
 		args := r.query.Args
-		r.whereConditions = append(r.whereConditions, map[string]interface{}{"query": strings.Join(columns, " AND "), "args": args})
+		r.whereConditions = appendCondition(r.whereConditions, fieldCondition{alias: alias, columns: columns, format: "%s = ?", operator: " AND "}, args)
 		r.query.Args = nil
 	}
 	return r
 }
 
 func (r *SQL) insert() *SQL {
-	columns, err := r.fieldsToArgs(
-		r.Model,
-		func(key string, n int, opts tagOptions) string {
-			return key
-		},
-	)
-	if err != nil {
-		panic(err.Error())
-	}
-	columns = append(columns, "create_date", "write_date")
-	r.query.Args = append(r.query.Args, time.Now().UTC(), time.Now().UTC())
-	params := make([]string, 0)
-	for i := 1; i <= len(r.query.Args); i++ {
-		params = append(params, fmt.Sprintf(`$%d`, i))
-	}
-	var buff strings.Builder
-	buff.Reset()
-	buff.WriteString("INSERT INTO ")
-	buff.WriteString(r.Model.(Model).TableName())
-	buff.WriteString(" (")
-	buff.WriteString(strings.Join(columns, ", "))
-	buff.WriteString(") ")
-	buff.WriteString("VALUES (")
-	buff.WriteString(strings.Join(params, ", "))
-	buff.WriteString(")")
-	r.query.Query = buff.String()
-	buff.Reset()
+	if _, ok := r.tableName(r.Model); !ok {
+		return r
+	}
+	if _, err := r.TagsToField(r.TagName, r.Model); err != nil {
+		r.addError(err)
+		return r
+	}
+	audit, _ := auditOf(r.Model)
+	r.insertion = &insertion{rows: []interface{}{r.Model}, audit: audit.columns(true), skip: audit.names()}
+	return r
+}
+
+func (r *SQL) upsert(columns []string) *SQL {
+	if len(columns) < 1 && len(r.ID) > 0 {
+		columns = []string{r.ID}
+	}
+	audit, _ := auditOf(r.Model)
+	r.conflict = &conflict{columns: columns, created: audit.created()}
 	return r
 }
 
+// onConflict renders the conflict clause of an upsert of the inserted
+// columns, only DoNothing may go without a conflict target.
+func (r *SQL) onConflict(st *statement, inserted []string) string {
+	c := r.conflict
+	if len(c.columns) < 1 && !c.nothing {
+		st.errs = append(st.errs, fmt.Errorf("upsert of %T has no conflict target, set the id or the conflict columns", r.Model))
+	}
+	update := c.update
+	if c.nothing {
+		update = nil
+	} else if len(update) < 1 {
+		for _, column := range inserted {
+			if contains(c.created, column) || contains(c.columns, column) {
+				continue
+			}
+			update = append(update, column)
+		}
+	}
+	return r.query.Dialect().OnConflict(r.idents(c.columns), r.idents(update))
+}
+
 func (r *SQL) inserts() *SQL {
-	var buff strings.Builder
-	buff.Reset()
-	t := reflect.TypeOf(r.Model)
 	val := reflect.ValueOf(r.Model)
-	rows := make([][]string, 0)
-	if t.Kind() == reflect.Slice {
-		for i := 0; i < val.Len(); i++ {
-			model := val.Index(i).Interface()
-			f, err := r.fieldsToArgs(model, func(key string, n int, opts tagOptions) string {
-				return fmt.Sprintf(`$%d`, n)
-			})
-			if err != nil {
-				panic(err.Error())
+	if val.Kind() != reflect.Slice || val.Len() < 1 {
+		r.addError(fmt.Errorf("inserts requires a non empty slice of models, got %T", r.Model))
+		return r
+	}
+	if _, ok := r.tableName(val.Index(0).Interface()); !ok {
+		return r
+	}
+	first := reflect.TypeOf(val.Index(0).Interface())
+	rows := make([]interface{}, val.Len())
+	for i := range rows {
+		rows[i] = val.Index(i).Interface()
+		if t := reflect.TypeOf(rows[i]); t != first {
+			r.addError(fmt.Errorf("inserts requires models of one type, got %v and %v", first, t))
+			return r
+		}
+		if _, _, err := tagsToField(r.TagName, rows[i], nil); err != nil {
+			r.addError(err)
+			return r
+		}
+	}
+	if _, err := r.TagsToField(r.TagName, rows[0]); err != nil {
+		r.addError(err)
+		return r
+	}
+	audit, _ := auditOf(r.Model)
+	r.insertion = &insertion{rows: rows, audit: audit.columns(true), skip: audit.names()}
+	return r
+}
+
+// insertQuery writes the INSERT of the rows and returns its columns, the
+// non-empty columns of any row followed by the audit columns. Every row
+// writes every column, an empty value is written as is and a NULL one as
+// NULL, so the rows always match the column list.
+func (r *SQL) insertQuery(st *statement) []string {
+	dialect := r.query.Dialect()
+	m, ok := r.insertion.rows[0].(Model)
+	if !ok {
+		return nil
+	}
+	keys := make([]string, 0)
+	for _, row := range r.insertion.rows {
+		fields, _, err := tagsToField(r.TagName, row, nil)
+		if err != nil {
+			st.errs = append(st.errs, err)
+			return nil
+		}
+		for k := range fields {
+			if !contains(r.insertion.skip, k) && !contains(keys, k) {
+				keys = append(keys, k)
 			}
-			f = append(f, fmt.Sprintf(`$%d`, len(r.query.Args)+1), fmt.Sprintf(`$%d`, len(r.query.Args)+2))
-			r.query.Args = append(r.query.Args, time.Now().UTC(), time.Now().UTC())
+		}
+	}
+	sort.Strings(keys)
 
-			rows = append(rows, f)
+	values := make([]string, 0, len(r.insertion.rows))
+	for _, row := range r.insertion.rows {
+		fields, _, err := tagsToField(r.TagName, row, keys)
+		if err != nil {
+			st.errs = append(st.errs, err)
+			return nil
+		}
+		params := make([]string, 0, len(keys)+len(r.insertion.audit))
+		for _, k := range keys {
+			var value interface{}
+			if field, ok := fields[k]; ok {
+				value = field[0]
+			}
+			st.args = append(st.args, value)
+			params = append(params, dialect.Placeholder(len(st.args)))
+		}
+		for _, column := range r.insertion.audit {
+			if _, ok := column.value.(dbNow); ok {
+				params = append(params, dialect.Now())
+				continue
+			}
+			st.args = append(st.args, r.bindArg(column.value))
+			params = append(params, dialect.Placeholder(len(st.args)))
 		}
+		values = append(values, fmt.Sprintf("(%s)", strings.Join(params, ", ")))
+	}
+	for _, column := range r.insertion.audit {
+		keys = append(keys, column.name)
+	}
+	st.WriteString("INSERT INTO ")
+	st.WriteString(r.qualifiedName(m))
+	st.WriteString(" (")
+	st.WriteString(strings.Join(r.idents(keys), ", "))
+	st.WriteString(") VALUES ")
+	st.WriteString(strings.Join(values, ", "))
+	return keys
+}
+
+func (r *SQL) updates() *SQL {
+	if _, ok := r.tableName(r.Model); !ok {
+		return r
 	}
-	cols, err := r.TagsToField(r.TagName, val.Index(0).Interface())
+	if _, err := r.TagsToField(r.TagName, r.Model); err != nil {
+		r.addError(err)
+		return r
+	}
+	audit, _ := auditOf(r.Model)
+	r.update = &updateSet{audit: audit.columns(false), skip: audit.names()}
+	return r
+}
+
+// updateQuery writes the UPDATE of the non-empty fields of the model, or
+// of the Fields mask whatever their value, followed by the audit columns.
+func (r *SQL) updateQuery(st *statement) {
+	dialect := r.query.Dialect()
+	m, ok := r.Model.(Model)
+	if !ok {
+		return
+	}
+	fields, _, err := tagsToField(r.TagName, r.Model, r.update.fields)
 	if err != nil {
-		panic(err.Error())
+		st.errs = append(st.errs, err)
+		return
 	}
 	keys := make([]string, 0)
-	for k := range cols {
+	for k := range fields {
 		keys = append(keys, k)
 	}
 	sort.Strings(keys)
-	keys = append(keys, "create_date", "write_date")
-
-	buff.Reset()
-	for n, columns := range rows {
-		buff.WriteString("(")
-		for m, column := range columns {
-			buff.WriteString(column)
-			if m < len(columns)-1 {
-				buff.WriteString(", ")
-			}
+	columns := make([]string, 0)
+	for _, k := range keys {
+		if k == r.ID || contains(r.update.skip, k) || (len(r.update.fields) > 0 && !contains(r.update.fields, k)) {
+			continue
 		}
-		buff.WriteString(")")
-		if n < len(rows)-1 {
-			buff.WriteString(", ")
-		}
-	}
-	value := buff.String()
-	buff.Reset()
-	buff.WriteString("INSERT INTO ")
-	buff.WriteString(val.Index(0).Interface().(Model).TableName())
-	buff.WriteString(" (")
-	buff.WriteString(strings.Join(keys, ", "))
-	buff.WriteString(") ")
-	buff.WriteString("VALUES ")
-	buff.WriteString(value)
-	r.query.Query = buff.String()
-	return r
+		// placeholders must follow the args order for positional dialects
+		st.args = append(st.args, fields[k][0])
+		columns = append(columns, fmt.Sprintf("%s = %s", r.ident(k), dialect.Placeholder(len(st.args))))
+	}
+	for _, column := range r.update.audit {
+		if _, ok := column.value.(dbNow); ok {
+			columns = append(columns, fmt.Sprintf("%s = %s", r.ident(column.name), dialect.Now()))
+			continue
+		}
+		st.args = append(st.args, r.bindArg(column.value))
+		columns = append(columns, fmt.Sprintf("%s = %s", r.ident(column.name), dialect.Placeholder(len(st.args))))
+	}
+	if len(columns) < 1 {
+		st.errs = append(st.errs, fmt.Errorf("updates of %T has no column to set", r.Model))
+	}
+	st.WriteString("UPDATE ")
+	st.WriteString(r.qualifiedName(m))
+	st.WriteString(" SET ")
+	st.WriteString(strings.Join(columns, ", "))
 }
 
-func (r *SQL) updates() *SQL {
-	var buff strings.Builder
-	buff.Reset()
-
-	r.query.Args = append(r.query.Args, time.Now().UTC())
+func (r *SQL) delete() *SQL {
+	if _, ok := r.tableName(r.Model); !ok {
+		return r
+	}
+	deleted := r.softDeleteColumn(r.Model)
 	columns, err := r.fieldsToArgs(
 		r.Model,
 		func(key string, n int, opts tagOptions) string {
-			if !strings.Contains(key, r.ID) {
-				return fmt.Sprintf(`%s = $%d`, key, n)
+			if key == deleted {
+				return ""
 			}
-			return ""
+			return key
 		},
 	)
 	if err != nil {
-		panic(err.Error())
-	}
-	columns = append(columns, "write_date = $1")
-	buff.WriteString("UPDATE ")
-	buff.WriteString(r.Model.(Model).TableName())
-	buff.WriteString(" SET ")
-	buff.WriteString(strings.Join(columns, ", "))
-	r.query.Query = buff.String()
-	buff.Reset()
+		r.addError(err)
+		return r
+	}
+	r.deletion = &deletion{soft: deleted}
+	r.scope = fieldCondition{}
+	if len(deleted) > 0 {
+		r.scope = fieldCondition{columns: []string{deleted}, format: "%s IS NULL"}
+	}
+	if len(columns) > 0 {
+		args := r.query.Args
+		r.whereConditions = appendCondition(r.whereConditions, fieldCondition{columns: columns, format: "%s = ?", operator: " AND "}, args)
+		r.query.Args = nil
+	}
 	return r
 }
 
+// deleteQuery writes the DELETE of the model, or the UPDATE of its soft
+// delete column. A delete without condition would remove every row of
+// the table, so it fails to build.
+func (r *SQL) deleteQuery(st *statement) {
+	m, ok := r.Model.(Model)
+	if !ok {
+		return
+	}
+	if len(r.whereConditions) < 1 {
+		st.errs = append(st.errs, fmt.Errorf("delete of %T has no condition, set a field of the model or use Where", r.Model))
+	}
+	if len(r.deletion.soft) > 0 {
+		st.WriteString("UPDATE ")
+		st.WriteString(r.qualifiedName(m))
+		st.WriteString(" SET ")
+		st.WriteString(fmt.Sprintf("%s = %s", r.ident(r.deletion.soft), r.query.Dialect().Now()))
+		return
+	}
+	st.WriteString("DELETE FROM ")
+	st.WriteString(r.qualifiedName(m))
+}
+
+// softDeleteColumn returns the soft delete column of the model, empty
+// when the model is hard deleted or the scope is unscoped.
+func (r *SQL) softDeleteColumn(model interface{}) string {
+	if r.unscoped {
+		return ""
+	}
+	if m, ok := model.(SoftDeleter); ok && !isNilModel(model) {
+		return m.DeletedColumn()
+	}
+	t := reflect.TypeOf(model)
+	if t == nil {
+		return ""
+	}
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return ""
+	}
+	for _, f := range taggedFields(r.TagName, t) {
+		if isValidTag(f.name) && f.opts.Contains("softdelete") {
+			return f.name
+		}
+	}
+	return ""
+}
+
 func (r *SQL) fieldsToArgs(model interface{}, fn formatField) ([]string, error) {
 	fields, err := r.TagsToField(r.TagName, model)
 	if err != nil {
@@ -385,23 +1375,29 @@ func (r *SQL) fieldsToArgs(model interface{}, fn formatField) ([]string, error)
 			continue
 		}
 		f = append(f, field)
-		arg, ok := fields[k][0].(string)
-		if !ok {
-			if integer, err := strconv.Atoi(fields[k][0].(string)); err == nil {
-				r.query.Args = append(r.query.Args, integer)
-				continue
-			}
-			if b, err := strconv.ParseBool(fields[k][0].(string)); err == nil {
-				r.query.Args = append(r.query.Args, b)
-				continue
-			}
-		}
-		r.query.Args = append(r.query.Args, arg)
+		r.query.Args = append(r.query.Args, fields[k][0])
 	}
 	return f, nil
 }
 
+// TagsToField maps the tagged columns of the model to their native value
+// and tag options, leaving out empty values unless tagged notnull, e.g.
+// `sql:"enabled,notnull"` keeps enabled = false.
 func (r *SQL) TagsToField(tag string, value interface{}) (result map[string][]interface{}, err error) {
+	result, id, err := tagsToField(tag, value, nil)
+	if err != nil {
+		return nil, err
+	}
+	r.ID = id
+	return result, nil
+}
+
+// tagsToField is TagsToField keeping the masked columns whatever their
+// value, a NULL one is mapped to nil.
+func tagsToField(tag string, value interface{}, mask []string) (result map[string][]interface{}, id string, err error) {
+	if isNilModel(value) {
+		return nil, "", fmt.Errorf("model %T is nil", value)
+	}
 	fn := func() (err error) {
 		defer func() {
 			if e := recover(); e != nil {
@@ -418,58 +1414,70 @@ func (r *SQL) TagsToField(tag string, value interface{}) (result map[string][]in
 	}
 	result = make(map[string][]interface{})
 	t := reflect.ValueOf(value).Elem()
-	for i := 0; i < t.NumField(); i++ {
-		val := t.Field(i)
-		field := t.Type().Field(i)
-		tagVal := field.Tag.Get(tag)
-		name, opts := parseTag(tagVal)
+	if t.Kind() != reflect.Struct {
+		return nil, "", fmt.Errorf("model %T is not a struct", value)
+	}
+	for _, f := range taggedFields(tag, t.Type()) {
+		field, name, opts := f.field, f.name, f.opts
 		if !isValidTag(name) {
 			name = ""
 		}
-		if strings.EqualFold(field.Name, "ID") {
-			r.ID = name
+		if strings.EqualFold(field.Name, "ID") && (len(id) < 1 || f.depth == 0) {
+			id = name
 		}
-		if isEmptyValue(val) || len(tagVal) < 1 {
+		val, ok := fieldOf(t, f.index)
+		if !ok {
 			continue
 		}
-		switch val.Interface().(type) {
-		case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
-			result[name] = append(result[name], fmt.Sprintf("%v", val.Interface()))
-		case *bool:
-			result[name] = append(result[name], fmt.Sprintf("%v", val.Elem().Interface()))
-		case NullString:
-			if !val.Interface().(NullString).Valid {
-				continue
-			}
-			result[name] = append(result[name], val.Interface().(NullString).String)
-		case NullInt64:
-			if !val.Interface().(NullInt64).Valid {
-				continue
-			}
-			result[name] = append(result[name], fmt.Sprintf("%v", val.Interface().(NullInt64).Int64))
-		case NullTime:
-			if !val.Interface().(NullTime).Valid {
-				continue
-			}
-			result[name] = append(result[name], fmt.Sprintf("%v", val.Interface().(NullTime).Time.UTC().Format(time.RFC3339)))
-		case NullFloat64:
-			if !val.Interface().(NullFloat64).Valid {
-				continue
-			}
-			result[name] = append(result[name], fmt.Sprintf("%v", val.Interface().(NullFloat64).Float64))
-		case NullBool:
-			if !val.Interface().(NullBool).Valid {
-				continue
+		masked := len(name) > 0 && contains(mask, name)
+		if len(name) < 1 || (isEmptyValue(val) && !masked && !opts.Contains("notnull")) {
+			continue
+		}
+		if isNullValue(val) {
+			if masked {
+				result[name] = []interface{}{nil, opts}
 			}
-			result[name] = append(result[name], fmt.Sprintf("%v", val.Interface().(NullBool).Bool))
-		case time.Time:
-			result[name] = append(result[name], fmt.Sprintf("%v", val.Interface().(time.Time).UTC().Format(time.RFC3339)))
-		default:
-			result[name] = append(result[name], fmt.Sprintf("%v", val.Interface()))
+			continue
+		}
+		arg, err := fieldValue(val)
+		if err != nil {
+			return nil, "", fmt.Errorf("field %s: %v", field.Name, err)
 		}
+		result[name] = append(result[name], arg)
 		result[name] = append(result[name], opts)
 	}
-	return result, nil
+	return result, id, nil
+}
+
+// modelColumns lists the columns tagged on the model in field order.
+func modelColumns(tag string, model interface{}) []string {
+	t := reflect.TypeOf(model)
+	if t == nil {
+		return nil
+	}
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+	columns := make([]string, 0)
+	for _, f := range taggedFields(tag, t) {
+		if !isValidTag(f.name) {
+			continue
+		}
+		columns = append(columns, f.name)
+	}
+	return columns
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
 }
 
 func isEmptyValue(v reflect.Value) bool {
@@ -486,6 +1494,46 @@ func isEmptyValue(v reflect.Value) bool {
 		return v.Float() == 0
 	case reflect.Interface, reflect.Ptr:
 		return v.IsNil()
+	case reflect.Struct:
+		// a Valuer decides on its own value, e.g. NullTime
+		if v.Type().Implements(valuerType) || reflect.PtrTo(v.Type()).Implements(valuerType) {
+			return false
+		}
+		return v.IsZero()
+	}
+	return false
+}
+
+var valuerType = reflect.TypeOf((*driver.Valuer)(nil)).Elem()
+
+// fieldValue returns the native value bound for the field, the value of
+// a driver.Valuer or of the pointed to value, with times in UTC.
+func fieldValue(v reflect.Value) (interface{}, error) {
+	value := v.Interface()
+	if valuer, ok := value.(driver.Valuer); ok {
+		var err error
+		if value, err = valuer.Value(); err != nil {
+			return nil, err
+		}
+	} else if v.Kind() == reflect.Ptr {
+		return fieldValue(v.Elem())
+	}
+	if t, ok := value.(time.Time); ok {
+		return t.UTC(), nil
+	}
+	return value, nil
+}
+
+// isNullValue reports whether the field binds NULL, a nil pointer or
+// an invalid NullString, NullInt64 and the like.
+func isNullValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Interface, reflect.Ptr, reflect.Map, reflect.Slice:
+		return v.IsNil()
+	}
+	if valuer, ok := v.Interface().(driver.Valuer); ok {
+		value, err := valuer.Value()
+		return err == nil && value == nil
 	}
 	return false
 }
@@ -9,9 +9,14 @@
 package tyr
 
 import (
+	"database/sql/driver"
+	"fmt"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/lib/pq"
 	"github.com/suryakencana007/mimir"
 
 	"github.com/stretchr/testify/assert"
@@ -24,6 +29,18 @@ func TestRawQuery_AND_OR(t *testing.T) {
 	assert.Equal(t, 4, len(args))
 }
 
+func TestRawQuery_OR_Where(t *testing.T) {
+	query, args := Build().From(Game{}, "g").Or(&Game{Code: "a", Title: "b"}, "g").Where("g.rate > ?", 3).ToSQL()
+	assert.Contains(t, query, "WHERE (g.game_code = $1 OR g.game_title = $2) AND g.rate > $3 LIMIT")
+	assert.Equal(t, []interface{}{"a", "b", 3}, args)
+
+	query, _ = Build().From(Game{}, "g").Where("g.rate > ? OR g.rate IS NULL", 3).And(&Game{Code: "a"}, "g").ToSQL()
+	assert.Contains(t, query, "WHERE (g.rate > $1 OR g.rate IS NULL) AND g.game_code = $2 LIMIT")
+
+	query, _ = Build().From(Game{Enabled: true}, "g").Where("g.rate > ? OR g.rate IS NULL", 3).ToSQL()
+	assert.Contains(t, query, "WHERE g.enabled = $1 AND (g.rate > $2 OR g.rate IS NULL) LIMIT")
+}
+
 func TestRawQuery_AND(t *testing.T) {
 	rawSelect := Build()
 	query, args := rawSelect.From(Game{}, "g").And(&Game{Code: "code", Enabled: true}, "g").ToSQL()
@@ -49,6 +66,111 @@ func TestRawQuery_Join(t *testing.T) {
 	assert.Equal(t, len(args), 3)
 }
 
+func TestRawQuery_OrderBy(t *testing.T) {
+	query, args := Build().From(Game{}, "g").
+		Join(User{}, "u", "u.id = g.user_id").
+		And(&Game{Enabled: true}, "g").
+		Or(&Game{Code: "code"}, "g").
+		OrderBy(Asc("g.game_title"), Desc("g.rate").NullsLast(), "u.name").
+		Limit(10).Page(2).ToSQL()
+	assert.Equal(t, "SELECT g.*, u.* FROM ref_game g JOIN ref_user u ON u.id = g.user_id WHERE g.enabled = $1 OR g.game_code = $2 ORDER BY g.game_title ASC, g.rate DESC NULLS LAST, u.name LIMIT 10 OFFSET 10", query)
+	assert.Equal(t, 2, len(args))
+
+	query, _ = Build().SetDialect(MYSQL).From(Game{}, "g").OrderBy(Asc("g.rate").NullsFirst(), Desc("g.release").NullsLast()).ToSQL()
+	assert.Contains(t, query, "ORDER BY g.rate IS NOT NULL, g.rate ASC, g.release IS NULL, g.release DESC LIMIT")
+}
+
+func TestRawQuery_GroupByHaving(t *testing.T) {
+	query, args := Build().From(Game{Code: "code"}, "g").
+		Where("g.rate > ?", 10).
+		GroupBy("g.game_code", "g.enabled").
+		Having("COUNT(g.game_id) > ?", 1).
+		Having("MAX(g.rate) < ?", 90).
+		OrderBy(Desc("g.game_code")).
+		ToSQL()
+	assert.Equal(t, "SELECT g.* FROM ref_game g WHERE g.game_code = $1 AND g.rate > $2 GROUP BY g.game_code, g.enabled HAVING COUNT(g.game_id) > $3 AND MAX(g.rate) < $4 ORDER BY g.game_code DESC LIMIT 100 OFFSET 0", query)
+	assert.Equal(t, []interface{}{"code", 10, 1, 90}, args)
+}
+
+func TestRawQuery_Select(t *testing.T) {
+	query, args := Build().From(Game{Code: "code"}, "g").Select("g.game_id", "g.game_title").ToSQL()
+	assert.Equal(t, "SELECT g.game_id, g.game_title FROM ref_game g WHERE g.game_code = $1 LIMIT 100 OFFSET 0", query)
+	assert.Equal(t, 1, len(args))
+
+	query, _ = Build().From(Game{}, "g").Join(User{}, "u", "u.id = g.user_id").Select().ToSQL()
+	assert.Equal(t, "SELECT g.game_id, g.game_code, g.game_title, g.game_description, g.enabled, g.rate, g.release, "+
+		"u.id AS u__id, u.name AS u__name FROM ref_game g JOIN ref_user u ON u.id = g.user_id LIMIT 100 OFFSET 0", query)
+}
+
+func TestRawQuery_WhereExpr(t *testing.T) {
+	query, args := Build().From(Game{Code: "code"}, "g").
+		Where(Or(Eq("g.enabled", true), And(Gt("g.rate", 10), Lt("g.rate", 90)))).
+		Where("g.game_title <> ?", "DOTA2").
+		Where(Not(In("g.game_id", []int{1, 2}))).
+		GroupBy("g.game_code").
+		Having(Gt("COUNT(g.game_id)", 1)).
+		ToSQL()
+	assert.Equal(t, "SELECT g.* FROM ref_game g WHERE g.game_code = $1 AND (g.enabled = $2 OR (g.rate > $3 AND g.rate < $4)) "+
+		"AND g.game_title <> $5 AND NOT (g.game_id IN ($6, $7)) GROUP BY g.game_code HAVING COUNT(g.game_id) > $8 LIMIT 100 OFFSET 0", query)
+	assert.Equal(t, []interface{}{"code", true, 10, 90, "DOTA2", 1, 2, 1}, args)
+}
+
+func TestRawQuery_WhereSlice(t *testing.T) {
+	query, args := Build().From(Game{Code: "code"}, "g").
+		Where("g.game_id IN (?) AND g.rate > ?", []int{1, 2, 3}, 10).
+		Where(Raw("g.game_title IN (?)", []string{"DOTA2"})).
+		ToSQL()
+	assert.Equal(t, "SELECT g.* FROM ref_game g WHERE g.game_code = $1 AND g.game_id IN ($2, $3, $4) AND g.rate > $5 AND g.game_title IN ($6) LIMIT 100 OFFSET 0", query)
+	assert.Equal(t, []interface{}{"code", 1, 2, 3, 10, "DOTA2"}, args)
+
+	query, args = Build().SetDialect(MYSQL).From(Game{}, "g").Where("g.game_id IN (?)", []int64{1, 2}).ToSQL()
+	assert.Contains(t, query, "WHERE g.game_id IN (?, ?) LIMIT")
+	assert.Equal(t, []interface{}{int64(1), int64(2)}, args)
+
+	query, args = Build().AnyArray().From(Game{}, "g").Where("g.game_id IN (?) AND g.game_code = ANY(?)", []int64{1, 2}, []string{"a"}).ToSQL()
+	assert.Contains(t, query, "WHERE g.game_id = ANY($1) AND g.game_code = ANY($2) LIMIT")
+	assert.Equal(t, pq.Array([]int64{1, 2}), args[0])
+
+	query, args = Build().From(Game{}, "g").Where("g.code = ?", []byte("code")).ToSQL()
+	assert.Contains(t, query, "WHERE g.code = $1 LIMIT")
+	assert.Equal(t, 1, len(args))
+
+	_, _, err := Build().From(Game{}, "g").Where("g.game_id IN (?)", []int{}).Build()
+	assert.Error(t, err)
+
+	_, _, err = Build().From(Game{}, "g").Where(Eq("g.rate", []int{1, 2})).Build()
+	assert.Error(t, err)
+}
+
+func TestRawQuery_JoinKinds(t *testing.T) {
+	query, args := Build().From(Game{Code: "code"}, "g").
+		LeftJoin(User{}, "creator", On("creator.id", "g.created_by"), Eq("creator.name", "budi")).
+		RightJoin(User{}, "editor", On("editor.id", "g.updated_by")).
+		FullJoin(Member{}, "m", "m.id = g.member_id").
+		CrossJoin(Post{}, "p").
+		Where("g.rate > ?", 10).
+		ToSQL()
+	assert.Equal(t, "SELECT g.*, creator.*, editor.*, m.*, p.* FROM ref_game g "+
+		"LEFT JOIN ref_user creator ON creator.id = g.created_by AND creator.name = $1 "+
+		"RIGHT JOIN ref_user editor ON editor.id = g.updated_by "+
+		"FULL JOIN ref_member m ON m.id = g.member_id "+
+		"CROSS JOIN posts p WHERE g.game_code = $2 AND g.rate > $3 LIMIT 100 OFFSET 0", query)
+	assert.Equal(t, []interface{}{"budi", "code", 10}, args)
+
+	query, _ = Build().From(Game{}, "g").
+		Join(User{}, "creator", On("creator.id", "g.created_by")).
+		Join(User{}, "editor", On("editor.id", "g.updated_by")).
+		Select().ToSQL()
+	assert.Contains(t, query, "creator.id AS creator__id, creator.name AS creator__name, editor.id AS editor__id, editor.name AS editor__name FROM")
+
+	_, _, err := Build().From(Game{}, "g").Join(User{}, "g", On("g.id", "g.user_id")).Build()
+	assert.Error(t, err)
+	_, _, err = Build().From(Game{}, "g").LeftJoin(User{}, "u").Build()
+	assert.Error(t, err)
+	_, _, err = Build().SetDialect(MYSQL).From(Game{}, "g").FullJoin(User{}, "u", On("u.id", "g.user_id")).Build()
+	assert.Error(t, err)
+}
+
 func TestRawQuery_From(t *testing.T) {
 	game := &Game{
 		ID:          507,
@@ -72,7 +194,7 @@ func TestRawQuery_UpdatesFewField(t *testing.T) {
 	rawUpdates := Build()
 	query, args := rawUpdates.Updates(game).Where("game_code = ? AND game_description > ?", game.Code, 23).ToSQL()
 	t.Log(query)
-	assert.Contains(t, query, "UPDATE ref_game SET enabled = $2, game_code = $3, game_description = $4, game_title = $5, write_date = $1 WHERE game_code = $6 AND game_description > $7 RETURNING game_id")
+	assert.Contains(t, query, "UPDATE ref_game SET enabled = $1, game_code = $2, game_description = $3, game_title = $4, write_date = $5 WHERE game_code = $6 AND game_description > $7 RETURNING game_id")
 	assert.Equal(t, len(args), 7)
 }
 
@@ -80,10 +202,117 @@ func TestRawQuery_Updates(t *testing.T) {
 	game := newGame()
 	rawUpdates := Build()
 	query, args := rawUpdates.Updates(game).Where("game_code = ? AND game_description > ?", game.Code, 23).ToSQL()
-	assert.Contains(t, query, "UPDATE ref_game SET enabled = $2, game_code = $3, game_description = $4, game_title = $5, rate = $6, release = $7, write_date = $1")
+	assert.Contains(t, query, "UPDATE ref_game SET enabled = $1, game_code = $2, game_description = $3, game_title = $4, rate = $5, release = $6, write_date = $7")
 	assert.Equal(t, len(args), 9)
 }
 
+func TestRawQuery_UpdatesZeroValues(t *testing.T) {
+	query, args := Build().Updates(&Player{ID: 1}).Where(Eq("id", 1)).ToSQL()
+	assert.Equal(t, "UPDATE players SET active = $1 WHERE id = $2 RETURNING id", query)
+	assert.Equal(t, false, args[0])
+
+	query, args = Build().From(Player{}, "p").ToSQL()
+	assert.Equal(t, "SELECT p.* FROM players p WHERE p.active = $1 LIMIT 100 OFFSET 0", query)
+	assert.Equal(t, []interface{}{false}, args)
+
+	update := Build().SetDialect(MYSQL).Updates(&Player{ID: 1, Name: "sury", Active: true}).Fields("score", "nick").Where(Eq("id", 1))
+	query, args = update.ToSQL()
+	assert.Equal(t, "UPDATE players SET nick = ?, score = ? WHERE id = ?", query)
+	assert.Equal(t, []interface{}{nil, 0, 1}, args)
+	_, again := update.ToSQL()
+	assert.Equal(t, args, again)
+
+	for _, q := range []*Query{
+		Build().Updates(&Player{ID: 1}).Fields("email"),
+		Build().Updates(&Player{ID: 1}).Fields("id"),
+		Build().From(Player{}, "p").Fields("score"),
+	} {
+		_, _, err := q.Build()
+		assert.Error(t, err)
+	}
+}
+
+type upperCode string
+
+func (c upperCode) Value() (driver.Value, error) {
+	return strings.ToUpper(string(c)), nil
+}
+
+type Measure struct {
+	ID      int64      `json:"id" sql:"id"`
+	Ratio   float64    `json:"ratio" sql:"ratio"`
+	Payload []byte     `json:"payload" sql:"payload"`
+	Taken   time.Time  `json:"taken" sql:"taken"`
+	Checked NullTime   `json:"checked" sql:"checked"`
+	Count   *int       `json:"count" sql:"count"`
+	Code    upperCode  `json:"code" sql:"code"`
+	Score   NullInt64  `json:"score" sql:"score"`
+	Note    NullString `json:"note" sql:"note"`
+}
+
+func (Measure) TableName() string {
+	return "measures"
+}
+
+func TestRawQuery_QuoteIdentifiers(t *testing.T) {
+	account := &Account{ID: 1, Order: 2, UserName: "sury"}
+
+	query, _ := Build().From(Account{}, "a").Join(Game{}, "g", On("g.user_id", "a.id")).Select().ToSQL()
+	assert.Equal(t, "SELECT a.id, a.order, a.userName, a.deleted_at, g.game_id AS g__game_id, g.game_code AS g__game_code, g.game_title AS g__game_title, g.game_description AS g__game_description, g.enabled AS g__enabled, g.rate AS g__rate, g.release AS g__release FROM billing.user a JOIN ref_game g ON g.user_id = a.id WHERE a.deleted_at IS NULL LIMIT 100 OFFSET 0", query)
+
+	pg := Build().QuoteIdentifiers()
+	query, _ = pg.From(account, "a").Select().ToSQL()
+	assert.Equal(t, `SELECT a."id", a."order", a."userName", a."deleted_at" FROM "billing"."user" a WHERE (a."id" = $1 AND a."order" = $2 AND a."userName" = $3) AND a."deleted_at" IS NULL LIMIT 100 OFFSET 0`, query)
+
+	query, _ = pg.Upsert(account).ToSQL()
+	assert.Equal(t, `INSERT INTO "billing"."user" ("id", "order", "userName") VALUES ($1, $2, $3) ON CONFLICT ("id") DO UPDATE SET "order" = EXCLUDED."order", "userName" = EXCLUDED."userName" RETURNING "id"`, query)
+
+	query, _ = pg.Updates(account).Where(Eq("id", 1)).Returning("order").ToSQL()
+	assert.Equal(t, `UPDATE "billing"."user" SET "order" = $1, "userName" = $2 WHERE id = $3 RETURNING "order"`, query)
+
+	query, _ = pg.Delete(&Account{ID: 1}).ToSQL()
+	assert.Equal(t, `UPDATE "billing"."user" SET "deleted_at" = now() WHERE ("id" = $1) AND "deleted_at" IS NULL RETURNING "id"`, query)
+
+	query, _ = Build().SetDialect(MYSQL).QuoteIdentifiers().Inserts([]*Account{account, account}).ToSQL()
+	assert.Equal(t, "INSERT INTO `billing`.`user` (`id`, `order`, `userName`) VALUES (?, ?, ?), (?, ?, ?)", query)
+
+	query, _ = Build().SetDialect(MYSQL).QuoteIdentifiers().From(Game{}, "g").Join(Account{}, "a", On("a.id", "g.user_id")).Select().Limit(1).ToSQL()
+	assert.Contains(t, query, "a.`userName` AS `a__userName`, a.`deleted_at` AS `a__deleted_at` FROM `ref_game` g JOIN `billing`.`user` a ON a.id = g.user_id LIMIT 0, 1")
+
+	query, args := Build().From(Account{ID: 1}, "a").Join(Game{}, "g", On("g.user_id", "a.id")).Or(&Account{Order: 2, UserName: "sury"}, "a").QuoteIdentifiers().ToSQL()
+	assert.Equal(t, `SELECT a.*, g.* FROM "billing"."user" a JOIN "ref_game" g ON g.user_id = a.id WHERE (a."id" = $1 OR a."order" = $2 OR a."userName" = $3) AND a."deleted_at" IS NULL LIMIT 100 OFFSET 0`, query)
+	assert.Equal(t, []interface{}{1, 2, "sury"}, args)
+
+	query, _ = Build().Delete(&Account{ID: 1}).QuoteIdentifiers().ToSQL()
+	assert.Equal(t, `UPDATE "billing"."user" SET "deleted_at" = now() WHERE ("id" = $1) AND "deleted_at" IS NULL RETURNING "id"`, query)
+}
+
+func TestRawQuery_TypedArgs(t *testing.T) {
+	taken := time.Date(2020, 5, 6, 7, 8, 9, 123456789, time.FixedZone("WIB", 7*3600))
+	count := 3
+	query, args := Build().Insert(&Measure{
+		ID:      9007199254740993,
+		Ratio:   0.25,
+		Payload: []byte{0, 1},
+		Taken:   taken,
+		Checked: Time(taken),
+		Count:   &count,
+		Code:    "ab",
+		Score:   Int64(7),
+	}).ToSQL()
+	assert.Equal(t, "INSERT INTO measures (checked, code, count, id, payload, ratio, score, taken) VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id", query)
+	assert.Equal(t, []interface{}{
+		taken.UTC(),
+		"AB",
+		3,
+		int64(9007199254740993),
+		[]byte{0, 1},
+		0.25,
+		int64(7),
+		taken.UTC(),
+	}, args)
+}
+
 func TestRawQuery_Insert(t *testing.T) {
 	game := newGame()
 	raw := Build().SetTag("sql")
@@ -106,6 +335,223 @@ func TestRawQuery_Inserts(t *testing.T) {
 	assert.Equal(t, len(args), 27)
 }
 
+func TestRawQuery_InsertsColumns(t *testing.T) {
+	query, args := Build().Inserts([]*Game{{ID: 1, Code: "a", Enabled: true}, {ID: 2, Code: "b", Rate: Int64(5)}}).ToSQL()
+	assert.Equal(t, "INSERT INTO ref_game (enabled, game_code, game_id, rate, create_date, write_date) VALUES ($1, $2, $3, $4, $5, $6), ($7, $8, $9, $10, $11, $12) RETURNING game_id", query)
+	assert.Equal(t, 12, len(args))
+	assert.Equal(t, []interface{}{true, "a", 1, nil}, args[:4])
+	assert.Equal(t, []interface{}{false, "b", 2, int64(5)}, args[6:10])
+
+	query, _ = Build().Upserts([]*Player{{ID: 1, Name: "a"}, {ID: 2}}).ToSQL()
+	assert.Equal(t, "INSERT INTO players (active, id, name) VALUES ($1, $2, $3), ($4, $5, $6) ON CONFLICT (id) DO UPDATE SET active = EXCLUDED.active, name = EXCLUDED.name RETURNING id", query)
+}
+
+func TestRawQuery_MySQLDialect(t *testing.T) {
+	query, args := Build().SetDialect(MYSQL).From(Game{}, "g").And(&Game{Code: "code", Enabled: true}, "g").Limit(10).Page(2).ToSQL()
+	assert.Contains(t, query, "SELECT g.* FROM ref_game g WHERE g.enabled = ? AND g.game_code = ? LIMIT 10, 10")
+	assert.Equal(t, 2, len(args))
+
+	game := newGame()
+	query, args = Build().SetDialect(MYSQL).Updates(game).Where("game_code = ?", game.Code).ToSQL()
+	assert.Equal(t, "UPDATE ref_game SET enabled = ?, game_code = ?, game_description = ?, game_title = ?, rate = ?, release = ?, write_date = ? WHERE game_code = ?", query)
+	assert.Equal(t, 8, len(args))
+
+	query, args = Build().SetDialect(MYSQL).Insert(game).ToSQL()
+	assert.Equal(t, "INSERT INTO ref_game (enabled, game_code, game_description, game_id, game_title, rate, release, create_date, write_date) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)", query)
+	assert.Equal(t, 9, len(args))
+}
+
+func TestRawQuery_Returning(t *testing.T) {
+	game := newGame()
+	query, _ := Build().Insert(game).Returning("game_id", "game_code").ToSQL()
+	assert.Contains(t, query, "VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING game_id, game_code")
+
+	query, _ = Build().From(Game{}, "g").Where("g.write_date > ?", time.Now()).ToSQL()
+	assert.NotContains(t, query, "RETURNING")
+}
+
+func TestRawQuery_Upsert(t *testing.T) {
+	game := newGame()
+	query, args := Build().Upsert(game).ToSQL()
+	assert.Equal(t, "INSERT INTO ref_game (enabled, game_code, game_description, game_id, game_title, rate, release, create_date, write_date) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) "+
+		"ON CONFLICT (game_id) DO UPDATE SET enabled = EXCLUDED.enabled, game_code = EXCLUDED.game_code, game_description = EXCLUDED.game_description, "+
+		"game_title = EXCLUDED.game_title, rate = EXCLUDED.rate, release = EXCLUDED.release, write_date = EXCLUDED.write_date RETURNING game_id", query)
+	assert.Equal(t, 9, len(args))
+
+	query, _ = Build().Upsert(game, "game_code").DoUpdate("game_title", "write_date").ToSQL()
+	assert.Contains(t, query, "ON CONFLICT (game_code) DO UPDATE SET game_title = EXCLUDED.game_title, write_date = EXCLUDED.write_date RETURNING game_id")
+
+	query, _ = Build().Upsert(game, "game_code").DoNothing().ToSQL()
+	assert.Contains(t, query, "ON CONFLICT (game_code) DO NOTHING RETURNING game_id")
+
+	query, _ = Build().SetDialect(MYSQL).Upsert(game).DoUpdate("game_title").ToSQL()
+	assert.Contains(t, query, "VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?) ON DUPLICATE KEY UPDATE game_title = VALUES(game_title)")
+
+	query, _ = Build().SetDialect(MYSQL).Upsert(game).DoNothing().ToSQL()
+	assert.Contains(t, query, "ON DUPLICATE KEY UPDATE game_id = game_id")
+
+	_, _, err := Build().Upsert(&Rating{GameID: 1, Score: 5}).Build()
+	assert.Error(t, err)
+
+	query, _ = Build().Upsert(&Rating{GameID: 1, Score: 5}).DoNothing().ToSQL()
+	assert.Equal(t, "INSERT INTO ratings (game_id, score) VALUES ($1, $2) ON CONFLICT DO NOTHING", query)
+
+	query, _ = Build().Upsert(&Rating{GameID: 1, Score: 5}, "game_id").ToSQL()
+	assert.Equal(t, "INSERT INTO ratings (game_id, score) VALUES ($1, $2) ON CONFLICT (game_id) DO UPDATE SET score = EXCLUDED.score", query)
+}
+
+func TestRawQuery_InsertLateSettings(t *testing.T) {
+	game := newGame()
+	base := Build().Upsert(game).DoUpdate("game_title")
+	query, _ := base.SetDialect(MYSQL).ToSQL()
+	assert.Equal(t, "INSERT INTO ref_game (enabled, game_code, game_description, game_id, game_title, rate, release, create_date, write_date) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?) ON DUPLICATE KEY UPDATE game_title = VALUES(game_title)", query)
+
+	query, _ = base.ToSQL()
+	assert.Contains(t, query, "VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) ON CONFLICT (game_id) DO UPDATE SET game_title = EXCLUDED.game_title")
+
+	query, _ = Build().Insert(&Player{Name: "sury"}).QuoteIdentifiers().SetDialect(MYSQL).ToSQL()
+	assert.Equal(t, "INSERT INTO `players` (`active`, `name`) VALUES (?, ?)", query)
+
+	query, _ = Build().Delete(&Post{ID: 1}).SetDialect(MYSQL).ToSQL()
+	assert.Equal(t, "UPDATE posts SET deleted_at = NOW() WHERE (id = ?) AND deleted_at IS NULL", query)
+}
+
+func TestRawQuery_Upserts(t *testing.T) {
+	games := []*Game{newGame(), newGame()}
+	query, args := Build().Upserts(games, "game_code").DoUpdate("game_title").ToSQL()
+	assert.Contains(t, query, "VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9), ($10, $11, $12, $13, $14, $15, $16, $17, $18) ON CONFLICT (game_code) DO UPDATE SET game_title = EXCLUDED.game_title")
+	assert.Equal(t, 18, len(args))
+}
+
+func TestRawQuery_Delete(t *testing.T) {
+	query, args := Build().Delete(&Game{ID: 507}).Where("game_code <> ?", "code").ToSQL()
+	assert.Equal(t, "DELETE FROM ref_game WHERE game_id = $1 AND game_code <> $2", query)
+	assert.Equal(t, 2, len(args))
+
+	query, args = Build().Delete(&Post{ID: 1}).ToSQL()
+	assert.Equal(t, "UPDATE posts SET deleted_at = now() WHERE (id = $1) AND deleted_at IS NULL RETURNING id", query)
+	assert.Equal(t, 1, len(args))
+
+	query, _ = Build().SetDialect(MYSQL).Delete(&Comment{ID: 1}).ToSQL()
+	assert.Equal(t, "UPDATE comments SET removed_at = NOW() WHERE (id = ?) AND removed_at IS NULL", query)
+
+	query, _ = Build().Unscoped().Delete(&Post{ID: 1}).ToSQL()
+	assert.Equal(t, "DELETE FROM posts WHERE id = $1", query)
+
+	query, _ = Build().Delete(&Game{}).Where("game_code = ?", "x").ToSQL()
+	assert.Equal(t, "DELETE FROM ref_game WHERE game_code = $1", query)
+
+	_, _, err := Build().Delete(&Game{}).Build()
+	assert.Error(t, err)
+	_, _, err = Build().Delete(&Post{}).Build()
+	assert.Error(t, err)
+	_, _, err = Build().Unscoped().Delete(Post{}).Build()
+	assert.Error(t, err)
+}
+
+func TestRawQuery_FromSoftDeleted(t *testing.T) {
+	query, args := Build().From(Post{Title: "tyr"}, "p").ToSQL()
+	assert.Equal(t, "SELECT p.* FROM posts p WHERE (p.title = $1) AND p.deleted_at IS NULL LIMIT 100 OFFSET 0", query)
+	assert.Equal(t, 1, len(args))
+
+	query, args = Build().From(Post{}, "p").Or(&Post{ID: 1, Title: "x"}, "p").ToSQL()
+	assert.Equal(t, "SELECT p.* FROM posts p WHERE (p.id = $1 OR p.title = $2) AND p.deleted_at IS NULL LIMIT 100 OFFSET 0", query)
+	assert.Equal(t, []interface{}{1, "x"}, args)
+
+	query, _ = Build().From(Post{}, "p").Where("p.id = ? OR p.title = ?", 1, "x").ToSQL()
+	assert.Equal(t, "SELECT p.* FROM posts p WHERE (p.id = $1 OR p.title = $2) AND p.deleted_at IS NULL LIMIT 100 OFFSET 0", query)
+
+	query, _ = Build().Unscoped().From(Post{}, "p").ToSQL()
+	assert.Equal(t, "SELECT p.* FROM posts p LIMIT 100 OFFSET 0", query)
+}
+
+func TestRawQuery_BuildError(t *testing.T) {
+	query, args, err := Build().From(Game{}, "g").Where("g.game_id = ?", 1).Build()
+	assert.NoError(t, err)
+	assert.Equal(t, "SELECT g.* FROM ref_game g WHERE g.game_id = $1 LIMIT 100 OFFSET 0", query)
+	assert.Equal(t, 1, len(args))
+
+	for _, q := range []*Query{
+		Build().From(struct{ ID int }{}, "g"),
+		Build().Join(User{}, "u", "u.id = g.user_id"),
+		Build().From(Game{}, "g").Join(User{}, "u", 1),
+		Build().From(Game{}, "g").OrderBy(1),
+		Build().From(Game{}, "g").Where(1),
+		Build().And(&Game{ID: 1}, "g"),
+		Build().Insert("game"),
+		Build().Inserts([]*Game{}),
+		Build().Updates(nil),
+		Build().Delete(struct{}{}),
+		Build().SetDialect("oracle").From(Game{}, "g"),
+		Build().From((*Game)(nil), "g"),
+		Build().From(Game{}, "g").Join((*User)(nil), "u", "u.id = g.user_id"),
+		Build().From(Game{}, "g").Or((*Game)(nil), "g"),
+		Build().Insert((*Game)(nil)),
+		Build().Upsert((*Game)(nil)),
+		Build().Inserts([]*Game{nil}),
+		Build().Inserts([]*Game{{ID: 1}, nil}),
+		Build().Inserts([]interface{}{&Game{ID: 1}, &User{ID: 2}}),
+		Build().Inserts([]interface{}{&Game{ID: 1}, Game{ID: 2}}),
+		Build().Updates((*Game)(nil)),
+		Build().Delete((*Game)(nil)),
+		Build().Delete((*Post)(nil)),
+		Build().From(Game{}, "g").Insert(&Player{Name: "sury"}),
+		Build().From(Game{Code: "x"}, "g").From(User{}, "u"),
+		Build().Insert(&Player{Name: "sury"}).Delete(&Player{ID: 1}),
+		Build().Updates(&Player{ID: 1, Name: "sury"}).Upsert(&Player{ID: 1}),
+	} {
+		query, args, err := q.Build()
+		assert.Error(t, err)
+		assert.IsType(t, BuildError{}, err)
+		assert.Empty(t, query)
+		assert.Nil(t, args)
+	}
+
+	query, _ = Build().Insert(1).ToSQL()
+	assert.Empty(t, query)
+}
+
+func TestRawQuery_Immutable(t *testing.T) {
+	base := Build().From(Game{Code: "code"}, "g").Where("g.enabled = ?", true)
+	first := base.Where("g.rate > ?", 10).OrderBy(Desc("g.rate")).Limit(10).Page(2)
+	second := base.Where(Eq("g.game_title", "DOTA2"))
+
+	query, args := base.ToSQL()
+	again, againArgs := base.ToSQL()
+	assert.Equal(t, "SELECT g.* FROM ref_game g WHERE g.game_code = $1 AND g.enabled = $2 LIMIT 100 OFFSET 0", query)
+	assert.Equal(t, query, again)
+	assert.Equal(t, args, againArgs)
+
+	query, args = first.ToSQL()
+	assert.Equal(t, "SELECT g.* FROM ref_game g WHERE g.game_code = $1 AND g.enabled = $2 AND g.rate > $3 ORDER BY g.rate DESC LIMIT 10 OFFSET 10", query)
+	assert.Equal(t, []interface{}{"code", true, 10}, args)
+
+	query, args = second.ToSQL()
+	assert.Equal(t, "SELECT g.* FROM ref_game g WHERE g.game_code = $1 AND g.enabled = $2 AND g.game_title = $3 LIMIT 100 OFFSET 0", query)
+	assert.Equal(t, []interface{}{"code", true, "DOTA2"}, args)
+
+	insert := Build().Insert(newGame())
+	query, _ = insert.ToSQL()
+	again, _ = insert.ToSQL()
+	assert.Equal(t, query, again)
+	assert.Equal(t, 1, strings.Count(again, "RETURNING"))
+}
+
+func TestRawQuery_ConcurrentBranches(t *testing.T) {
+	base := Build().From(Game{}, "g").Where("g.enabled = ?", true)
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			query, args := base.Where("g.game_id = ?", i).Limit(i + 1).Page(1).ToSQL()
+			assert.Equal(t, fmt.Sprintf("SELECT g.* FROM ref_game g WHERE g.enabled = $1 AND g.game_id = $2 LIMIT %d OFFSET 0", i+1), query)
+			assert.Equal(t, []interface{}{true, i}, args)
+		}(i)
+	}
+	wg.Wait()
+}
+
 func newGame() *Game {
 	return &Game{
 		ID:          507,
@@ -133,6 +579,10 @@ type Game struct {
 	Release     NullTime   `json:"release" sql:"release"`
 }
 
+func (Game) Audit() Audit {
+	return Audit{CreatedAt: "create_date", UpdatedAt: "write_date"}
+}
+
 func (Game) TableName() string {
 	return "ref_game"
 }
@@ -161,6 +611,69 @@ type Member struct {
 	Name      string    `json:"user_name" sql:"name"`
 }
 
+func (Member) Audit() Audit {
+	return Audit{CreatedAt: "create_date", UpdatedAt: "write_date"}
+}
+
 func (Member) TableName() string {
 	return "ref_member"
 }
+
+type Post struct {
+	ID        int      `json:"id" sql:"id"`
+	Title     string   `json:"title" sql:"title"`
+	DeletedAt NullTime `json:"deleted_at" sql:"deleted_at,softdelete"`
+}
+
+func (Post) TableName() string {
+	return "posts"
+}
+
+type Player struct {
+	ID     int        `json:"id" sql:"id"`
+	Name   string     `json:"name" sql:"name"`
+	Active bool       `json:"active" sql:"active,notnull"`
+	Score  int        `json:"score" sql:"score"`
+	Nick   NullString `json:"nick" sql:"nick"`
+}
+
+func (Player) TableName() string {
+	return "players"
+}
+
+type Account struct {
+	ID        int      `json:"id" sql:"id"`
+	Order     int      `json:"order" sql:"order"`
+	UserName  string   `json:"userName" sql:"userName"`
+	DeletedAt NullTime `json:"deleted_at" sql:"deleted_at,softdelete"`
+}
+
+func (Account) TableName() string {
+	return "user"
+}
+
+func (Account) SchemaName() string {
+	return "billing"
+}
+
+type Comment struct {
+	ID   int    `json:"id" sql:"id"`
+	Body string `json:"body" sql:"body"`
+}
+
+func (Comment) TableName() string {
+	return "comments"
+}
+
+func (Comment) DeletedColumn() string {
+	return "removed_at"
+}
+
+type Rating struct {
+	GameID int `json:"game_id" sql:"game_id"`
+	Score  int `json:"score" sql:"score"`
+}
+
+func (Rating) TableName() string {
+	return "ratings"
+}
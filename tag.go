@@ -9,6 +9,7 @@
 package tyr
 
 import (
+	"reflect"
 	"strings"
 )
 
@@ -46,3 +47,89 @@ func (o tagOptions) Contains(optionName string) bool {
 	}
 	return false
 }
+
+// taggedField is a field of a model with the name and options of its tag.
+type taggedField struct {
+	name  string
+	opts  tagOptions
+	field reflect.StructField
+	index []int
+	depth int
+}
+
+// taggedFields lists the exported fields of the struct type in field
+// order, the fields of untagged embedded structs in place of the struct.
+// Fields tagged "-" or nested are left out, and a field shadows the
+// fields of the same name embedded deeper.
+func taggedFields(tag string, t reflect.Type) []taggedField {
+	fields := make([]taggedField, 0, t.NumField())
+	collectFields(tag, t, nil, &fields)
+	shallowest := make(map[string]int)
+	for _, f := range fields {
+		if depth, ok := shallowest[f.name]; !ok || f.depth < depth {
+			shallowest[f.name] = f.depth
+		}
+	}
+	visible := fields[:0]
+	for _, f := range fields {
+		if len(f.name) < 1 || f.depth == shallowest[f.name] {
+			visible = append(visible, f)
+		}
+	}
+	return visible
+}
+
+func collectFields(tag string, t reflect.Type, index []int, fields *[]taggedField) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, opts := parseTag(field.Tag.Get(tag))
+		if name == "-" || opts.Contains("nested") {
+			continue
+		}
+		path := append(append([]int(nil), index...), i)
+		if isEmbedded(field, name) {
+			collectFields(tag, indirectType(field.Type), path, fields)
+			continue
+		}
+		if len(field.PkgPath) > 0 {
+			continue
+		}
+		*fields = append(*fields, taggedField{name: name, opts: opts, field: field, index: path, depth: len(index)})
+	}
+}
+
+// isEmbedded reports whether the fields of the struct field are promoted
+// as columns of the model, i.e. it embeds an untagged struct, e.g. a
+// BaseModel holding the id and audit columns.
+func isEmbedded(field reflect.StructField, name string) bool {
+	if !field.Anonymous || len(name) > 0 {
+		return false
+	}
+	if field.Type.Kind() == reflect.Ptr && len(field.PkgPath) > 0 {
+		// an unexported embedded pointer can not be allocated
+		return false
+	}
+	return indirectType(field.Type).Kind() == reflect.Struct
+}
+
+func indirectType(t reflect.Type) reflect.Type {
+	if t.Kind() == reflect.Ptr {
+		return t.Elem()
+	}
+	return t
+}
+
+// fieldOf returns the field of v at index, false when it is embedded in
+// a nil struct pointer.
+func fieldOf(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
@@ -9,7 +9,11 @@
 package tyr
 
 import (
+	"database/sql/driver"
 	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
 )
 
 func TestTagParsing(t *testing.T) {
@@ -30,3 +34,92 @@ func TestTagParsing(t *testing.T) {
 		}
 	}
 }
+
+type BaseModel struct {
+	ID        int       `json:"id" sql:"id"`
+	CreatedAt time.Time `json:"create_date" sql:"create_date"`
+	UpdatedAt time.Time `json:"write_date" sql:"write_date"`
+}
+
+type Note struct {
+	BaseModel
+	Title  string  `json:"title" sql:"title"`
+	Body   *string `json:"body" sql:"body"`
+	Secret string  `json:"-" sql:"-"`
+}
+
+func (Note) TableName() string {
+	return "notes"
+}
+
+type noteRef struct {
+	*BaseModel
+	ID    int    `sql:"note_id"`
+	Title string `sql:"title"`
+}
+
+func TestTaggedFields(t *testing.T) {
+	assert.Equal(t, []string{"id", "create_date", "write_date", "title", "body"}, modelColumns("sql", Note{}))
+	assert.Equal(t, []string{"id", "create_date", "write_date", "note_id", "title"}, modelColumns("sql", noteRef{}))
+
+	query, args := Build().Insert(&Note{Title: "a", Secret: "x"}).ToSQL()
+	assert.Equal(t, "INSERT INTO notes (title) VALUES ($1) RETURNING id", query)
+	assert.Equal(t, []interface{}{"a"}, args)
+
+	body := "b"
+	query, args = Build().Updates(&Note{BaseModel: BaseModel{ID: 3}, Body: &body, Secret: "x"}).Where(Eq("id", 3)).ToSQL()
+	assert.Equal(t, "UPDATE notes SET body = $1 WHERE id = $2 RETURNING id", query)
+	assert.Equal(t, []interface{}{"b", 3}, args)
+
+	cursor, err := Build().From(Note{}, "n").Keyset("", Desc("n.id")).NextCursor(Note{BaseModel: BaseModel{ID: 5}})
+	assert.NoError(t, err)
+	expected, _ := EncodeCursor(5)
+	assert.Equal(t, expected, cursor)
+
+	query, args = Build().Updates(&Note{BaseModel: BaseModel{ID: 2}, Title: "x"}).Where(Eq("id", 2)).ToSQL()
+	assert.Equal(t, "UPDATE notes SET title = $1 WHERE id = $2 RETURNING id", query)
+	assert.Equal(t, []interface{}{"x", 2}, args)
+
+	created := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	query, args = Build().Insert(&Note{BaseModel: BaseModel{CreatedAt: created}, Title: "a"}).ToSQL()
+	assert.Equal(t, "INSERT INTO notes (create_date, title) VALUES ($1, $2) RETURNING id", query)
+	assert.Equal(t, []interface{}{created, "a"}, args)
+
+	fields, id, err := tagsToField("sql", noteRef{Title: "a"}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "note_id", id)
+	assert.Equal(t, 1, len(fields))
+}
+
+func TestScanEmbedded(t *testing.T) {
+	created := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	columns := []string{"id", "create_date", "write_date", "title", "body", "secret"}
+	db := openFake(t, "scan embedded", columns,
+		[]driver.Value{int64(1), created, created, "a", "b", "x"},
+		[]driver.Value{int64(2), created, created, "c", nil, "x"},
+	)
+	defer db.Close()
+	defer openFake(t, "scan embedded pointer", []string{"note_id", "title", "id", "create_date", "write_date"},
+		[]driver.Value{int64(1), "a", int64(7), created, created},
+		[]driver.Value{int64(2), "b", nil, nil, nil},
+	).Close()
+
+	rows, err := db.Query("scan embedded")
+	assert.NoError(t, err)
+	var notes []Note
+	assert.NoError(t, ScanAll(rows, &notes))
+	body := "b"
+	assert.Equal(t, []Note{
+		{BaseModel: BaseModel{ID: 1, CreatedAt: created, UpdatedAt: created}, Title: "a", Body: &body},
+		{BaseModel: BaseModel{ID: 2, CreatedAt: created, UpdatedAt: created}, Title: "c"},
+	}, notes)
+
+	rows, err = db.Query("scan embedded pointer")
+	assert.NoError(t, err)
+	var refs []noteRef
+	assert.NoError(t, ScanAll(rows, &refs))
+	assert.Equal(t, []noteRef{
+		{BaseModel: &BaseModel{ID: 7, CreatedAt: created, UpdatedAt: created}, ID: 1, Title: "a"},
+		{ID: 2, Title: "b"},
+	}, refs)
+}
@@ -47,6 +47,18 @@ func (d *dbTracer) QueryRowCtx(ctx context.Context, fn func(rs *sql.Row) error,
 	return err
 }
 
+func (d *dbTracer) TxQueryCtx(ctx context.Context, tx *sql.Tx, fn func(rs *sql.Rows) error, query string, args ...interface{}) error {
+	span, ctxSpan := opentracing.StartSpanFromContext(ctx, "tracer.TxQueryCtx")
+	ext.DBStatement.Set(span, query)
+	ext.DBInstance.Set(span, "Master")
+	ext.DBType.Set(span, "sql")
+	span.SetTag("db.values", args)
+
+	err := d.DB.TxQueryCtx(ctxSpan, tx, fn, query, args...)
+	span.Finish()
+	return err
+}
+
 func (d *dbTracer) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
 	span, ctxSpan := opentracing.StartSpanFromContext(ctx, "tracer.ExecContext")
 	ext.DBStatement.Set(span, query)
@@ -71,6 +83,18 @@ func (d *dbTracer) TxExecContextWithID(ctx context.Context, tx *sql.Tx, query st
 	return ids, err
 }
 
+func (d *dbTracer) TxExecContextReturning(ctx context.Context, tx *sql.Tx, dest interface{}, query string, args ...interface{}) error {
+	span, ctxSpan := opentracing.StartSpanFromContext(ctx, "tracer.TxExecContextReturning")
+	ext.DBStatement.Set(span, query)
+	ext.DBInstance.Set(span, "Master")
+	ext.DBType.Set(span, "sql")
+	span.SetTag("db.values", args)
+
+	err := d.DB.TxExecContextReturning(ctxSpan, tx, dest, query, args...)
+	span.Finish()
+	return err
+}
+
 func (d *dbTracer) TxExecContext(ctx context.Context, tx *sql.Tx, query string, args ...interface{}) (affected int64, err error) {
 	span, ctxSpan := opentracing.StartSpanFromContext(ctx, "tracer.TxExecContext")
 	ext.DBStatement.Set(span, query)
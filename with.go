@@ -0,0 +1,76 @@
+package tyr
+
+import (
+	"fmt"
+	"strings"
+)
+
+// cte is a named common table expression, the body is a select *Query
+// or an Expr such as Raw.
+type cte struct {
+	name string
+	body interface{}
+}
+
+// With prefixes the select with the named common table expression, the
+// body is a select *Query or an Expr, e.g. Raw("SELECT ...", args...).
+// The name may list the columns, e.g. "tree(id, parent_id)", and is
+// selected from with a Model whose TableName returns it.
+func (s *Query) With(name string, body interface{}) *Query {
+	q := s.clone()
+	return q.raw.with(name, body)
+}
+
+// WithRecursive is With for a body referring to its own name, which
+// renders the WITH RECURSIVE clause.
+func (s *Query) WithRecursive(name string, body interface{}) *Query {
+	q := s.clone()
+	q.raw.recursive = true
+	return q.raw.with(name, body)
+}
+
+func (r *SQL) with(name string, body interface{}) *Query {
+	switch body.(type) {
+	case *Query, Expr:
+	default:
+		r.addError(fmt.Errorf("common table expression %T is not supported", body))
+		return r.query
+	}
+	for _, c := range r.ctes {
+		if c.name == name {
+			r.addError(fmt.Errorf("common table expression %q is already defined", name))
+			return r.query
+		}
+	}
+	r.ctes = append(r.ctes, cte{name: name, body: body})
+	return r.query
+}
+
+// withQuery writes the WITH clause, the bodies bind their args ahead
+// of the statement they prefix.
+func (r *SQL) withQuery(st *statement) {
+	if !r.isSelect() {
+		st.errs = append(st.errs, fmt.Errorf("WITH requires a select built by From"))
+	}
+	st.WriteString("WITH ")
+	if r.recursive {
+		st.WriteString("RECURSIVE ")
+	}
+	for i, c := range r.ctes {
+		if i > 0 {
+			st.WriteString(", ")
+		}
+		st.WriteString(c.name)
+		st.WriteString(" AS ")
+		switch body := c.body.(type) {
+		case *Query:
+			r.subquery(st, body)
+		case Expr:
+			query, args := body.Expr(r.query.Dialect())
+			st.WriteString("(")
+			r.bind(st, strings.TrimSpace(query), args)
+			st.WriteString(")")
+		}
+	}
+	st.WriteString(" ")
+}
@@ -0,0 +1,70 @@
+package tyr
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type tree struct {
+	ID       int64 `sql:"id"`
+	ParentID int64 `sql:"parent_id"`
+}
+
+func (tree) TableName() string {
+	return "tree"
+}
+
+func TestRawQuery_Subquery(t *testing.T) {
+	users := Build().From(User{}, "u").Select("u.id").Where(Eq("u.name", "sury"))
+
+	query, args := Build().From(Game{Enabled: true}, "g").Where(In("g.user_id", users)).Where(Gt("g.rate", 5)).ToSQL()
+	assert.Equal(t, "SELECT g.* FROM ref_game g WHERE g.enabled = $1 AND g.user_id IN (SELECT u.id FROM ref_user u WHERE u.name = $2) AND g.rate > $3 LIMIT 100 OFFSET 0", query)
+	assert.Equal(t, []interface{}{true, "sury", 5}, args)
+
+	query, args = Build().SetDialect(MYSQL).From(Game{}, "g").Where("g.user_id = ? AND g.rate > ?", users.Limit(1), 5).ToSQL()
+	assert.Equal(t, "SELECT g.* FROM ref_game g WHERE g.user_id = (SELECT u.id FROM ref_user u WHERE u.name = ? LIMIT 0, 1) AND g.rate > ? LIMIT 0, 100", query)
+	assert.Equal(t, []interface{}{"sury", 5}, args)
+
+	query, args = Build().From(Game{}, "g").Where("g.user_id IN (?) AND g.rate > ?", users, 5).ToSQL()
+	assert.Equal(t, "SELECT g.* FROM ref_game g WHERE g.user_id IN (SELECT u.id FROM ref_user u WHERE u.name = $1) AND g.rate > $2 LIMIT 100 OFFSET 0", query)
+	assert.Equal(t, []interface{}{"sury", 5}, args)
+
+	exists := Build().From(Comment{}, "c").Select("1").Where(On("c.post_id", "p.id"))
+	query, _ = Build().From(Post{}, "p").Where(NotExists(exists)).ToSQL()
+	assert.Equal(t, "SELECT p.* FROM posts p WHERE (NOT EXISTS (SELECT 1 FROM comments c WHERE (c.post_id = p.id) AND c.removed_at IS NULL)) AND p.deleted_at IS NULL LIMIT 100 OFFSET 0", query)
+
+	_, _, err := Build().From(Game{}, "g").Where(In("g.user_id", Build().Delete(User{ID: 1}))).Build()
+	assert.Error(t, err)
+	_, _, err = Build().From(Game{}, "g").Where(Exists(users.AddError(assert.AnError))).Build()
+	assert.Error(t, err)
+}
+
+func TestRawQuery_With(t *testing.T) {
+	rated := Build().From(Game{}, "g").Where(Gte("g.rate", 5))
+	query, args := Build().
+		With("rated", rated).
+		From(Game{}, "r").
+		Where(Eq("r.game_code", "x")).
+		ToSQL()
+	assert.Equal(t, "WITH rated AS (SELECT g.* FROM ref_game g WHERE g.rate >= $1) SELECT r.* FROM ref_game r WHERE r.game_code = $2 LIMIT 100 OFFSET 0", query)
+	assert.Equal(t, []interface{}{5, "x"}, args)
+
+	query, args = Build().
+		WithRecursive("tree(id, parent_id)", Raw("SELECT id, parent_id FROM category WHERE id = ? UNION ALL SELECT c.id, c.parent_id FROM category c JOIN tree t ON c.parent_id = t.id", 7)).
+		From(tree{}, "t").
+		Where(Neq("t.id", 7)).
+		ToSQL()
+	assert.Equal(t, "WITH RECURSIVE tree(id, parent_id) AS (SELECT id, parent_id FROM category WHERE id = $1 UNION ALL SELECT c.id, c.parent_id FROM category c JOIN tree t ON c.parent_id = t.id) SELECT t.* FROM tree t WHERE t.id <> $2 LIMIT 100 OFFSET 0", query)
+	assert.Equal(t, []interface{}{7, 7}, args)
+
+	query, _ = Build().With("rated", rated).From(Game{}, "r").CountQuery().ToSQL()
+	assert.Equal(t, "WITH rated AS (SELECT g.* FROM ref_game g WHERE g.rate >= $1) SELECT COUNT(*) FROM ref_game r", query)
+
+	_, _, err := Build().With("rated", rated).With("rated", rated).From(Game{}, "r").Build()
+	assert.Error(t, err)
+	_, _, err = Build().With("rated", "SELECT 1").From(Game{}, "r").Build()
+	assert.Error(t, err)
+	_, _, err = Build().With("rated", rated).Delete(Game{ID: 1}).Build()
+	assert.Error(t, err)
+}